@@ -1,21 +1,239 @@
 // GRaIL — Graphical Representation and Interpretation Language
 // Terminal flowchart editor + interpreter.
 //
-// Run: GOWORK=off go run ./cmd/grail/
+// Run: GOWORK=off go run ./cmd/grail/ [FILE]
+//
+// Subcommands:
+//
+//	grail diff [-view] A B            show what changed from A to B
+//	grail merge [-o OUT] BASE OURS THEIRS
+//	                                  three-way merge into OURS (or OUT)
+//	grail render FILE [-format txt|ansi|html|svg] [-o OUT]
+//	                                  draw the chart as text, ANSI, HTML or SVG
+//
+// To use grail as a git merge driver for chart files:
+//
+//	git config merge.grail.driver "grail merge %O %A %B"
+//	echo "*.grail merge=grail" >> .gitattributes
+//
+// Keys are rebound in grail/keys.json in the user's config directory
+// (e.g. ~/.config/grail/keys.json), which maps action names to keys:
+//
+//	{"palette": ["ctrl+k"], "run": ["R"]}
+//
+// The actions and their names are listed in internal/grailui/actions.go.
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	tea "charm.land/bubbletea/v2"
 	"github.com/wesen/grail/internal/grailui"
 )
 
 func main() {
-	p := tea.NewProgram(grailui.NewModel())
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "diff":
+			os.Exit(runDiff(args[1:]))
+		case "merge":
+			os.Exit(runMerge(args[1:]))
+		case "render":
+			os.Exit(runRender(args[1:]))
+		}
+	}
+
+	m := grailui.NewModel()
+	if len(args) > 0 {
+		var err error
+		if m, err = grailui.NewFileModel(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	keymap, err := loadKeymap()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	m.Keymap = keymap
+	runUI(m)
+}
+
+// loadKeymap binds the editor's actions to keys, as rebound by the key
+// config file if there is one (see grailui.KeyConfigPath).
+func loadKeymap() (grailui.Keymap, error) {
+	var cfg grailui.KeyConfig
+	if path, err := grailui.KeyConfigPath(); err == nil {
+		cfg, err = grailui.LoadKeyConfig(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return grailui.NewKeymap(cfg)
+}
+
+func runUI(m grailui.Model) {
+	p := tea.NewProgram(m)
 	if _, err := p.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// runDiff prints the changes from A to B, or shows them in the editor
+// with -view. Like diff(1) it exits 1 when the charts differ.
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	view := fs.Bool("view", false, "show the diff in the editor")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: grail diff [-view] A B")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	a, err := grailui.LoadGraph(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	b, err := grailui.LoadGraph(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	d := grailui.DiffGraphs(a, b)
+	if *view {
+		runUI(grailui.NewDiffModel(a, b, fs.Arg(0)+" → "+fs.Arg(1)))
+	} else {
+		fmt.Print(grailui.FormatDiff(d))
+	}
+	if d.Empty() {
+		return 0
+	}
+	return 1
+}
+
+// runMerge merges OURS and THEIRS against BASE. The result replaces OURS
+// unless -o is given, as git expects of a merge driver. Conflicts are
+// resolved in favour of keeping work, listed on stderr, and make the
+// command exit 1.
+func runMerge(args []string) int {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	out := fs.String("o", "", "write the result to `file` instead of OURS")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: grail merge [-o OUT] BASE OURS THEIRS")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 3 {
+		fs.Usage()
+		return 2
+	}
+
+	var graphs [3]*grailui.FlowGraph
+	for i := range graphs {
+		g, err := grailui.LoadGraph(fs.Arg(i))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		graphs[i] = g
+	}
+
+	merged, conflicts := grailui.MergeGraphs(graphs[0], graphs[1], graphs[2])
+	dest := *out
+	if dest == "" {
+		dest = fs.Arg(1)
+	}
+	if err := grailui.SaveGraph(dest, merged); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	for _, c := range conflicts {
+		fmt.Fprintf(os.Stderr, "conflict: %s\n", c)
+	}
+	if len(conflicts) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runRender draws a chart file the way the editor shows it, to stdout or
+// to -o. The format defaults to the output file's extension, else txt.
+func runRender(args []string) int {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	format := fs.String("format", "", "output format: "+strings.Join(grailui.ExportFormats, ", "))
+	out := fs.String("o", "", "write to `file` instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: grail render FILE [-format txt|ansi|html|svg] [-o OUT]")
+		fs.PrintDefaults()
+	}
+	pos := parseInterspersed(fs, args)
+	if len(pos) != 1 {
+		fs.Usage()
+		return 2
+	}
+	if *format == "" {
+		*format = "txt"
+		if ext := strings.TrimPrefix(filepath.Ext(*out), "."); slices.Contains(grailui.ExportFormats, ext) {
+			*format = ext
+		}
+	}
+	// Check the format before -o truncates anything
+	if !slices.Contains(grailui.ExportFormats, *format) {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (want one of %s)\n", *format, strings.Join(grailui.ExportFormats, ", "))
+		return 2
+	}
+
+	g, err := grailui.LoadGraph(pos[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	title := filepath.Base(pos[0])
+	if *out == "" {
+		err = grailui.ExportChart(os.Stdout, g, *format, title)
+	} else {
+		var f *os.File
+		if f, err = os.Create(*out); err == nil {
+			err = grailui.ExportChart(f, g, *format, title)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	return 0
+}
+
+// parseInterspersed parses flags that may appear before or after the
+// positional arguments, which it returns.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var pos []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,461 @@
+package grailui
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// An Action is an editor command. Actions are bound to keys, listed in
+// the help panel and run from the command palette; a key config file
+// can rebind them by name.
+type Action struct {
+	Name  string   // stable name, used in key config files
+	Title string   // shown in the command palette
+	Help  string   // short label in the help panel; empty to leave it out
+	Keys  []string // default keys
+	Run   func(m *Model) tea.Cmd
+}
+
+// do adapts a pointer method to an action that returns no command.
+func do(f func(m *Model)) func(m *Model) tea.Cmd {
+	return func(m *Model) tea.Cmd {
+		f(m)
+		return nil
+	}
+}
+
+// handler adapts a key handler that returns a new model to an action.
+func handler(f func(m Model) (tea.Model, tea.Cmd)) func(m *Model) tea.Cmd {
+	return func(m *Model) tea.Cmd {
+		next, cmd := f(*m)
+		*m = next.(Model)
+		return cmd
+	}
+}
+
+// actions is the registry of editor commands, in the order the help
+// panel and the command palette list them.
+var actions = []*Action{
+	{Name: "palette", Title: "Command palette", Help: "Palette", Keys: []string{"ctrl+p", ":"},
+		Run: do((*Model).openPalette)},
+
+	// Tools
+	{Name: "tool-select", Title: "Select tool", Help: "Select", Keys: []string{"s"},
+		Run: do(func(m *Model) { m.setTool(ToolSelect) })},
+	{Name: "tool-add", Title: "Add tool", Help: "Add", Keys: []string{"a"},
+		Run: do(func(m *Model) { m.setTool(ToolAdd) })},
+	{Name: "tool-connect", Title: "Connect tool", Help: "Connect", Keys: []string{"c"},
+		Run: do(func(m *Model) { m.setTool(ToolConnect) })},
+	{Name: "add-process", Title: "Add tool: process", Keys: []string{"1"},
+		Run: do(func(m *Model) { m.setAddType("process") })},
+	{Name: "add-decision", Title: "Add tool: decision", Keys: []string{"2"},
+		Run: do(func(m *Model) { m.setAddType("decision") })},
+	{Name: "add-terminal", Title: "Add tool: terminal", Keys: []string{"3"},
+		Run: do(func(m *Model) { m.setAddType("terminal") })},
+	{Name: "add-io", Title: "Add tool: input/output", Keys: []string{"4"},
+		Run: do(func(m *Model) { m.setAddType("io") })},
+	{Name: "add-connector", Title: "Add tool: connector", Keys: []string{"5"},
+		Run: do(func(m *Model) { m.setAddType("connector") })},
+
+	// Editing
+	{Name: "edit", Title: "Edit selection", Help: "Edit", Keys: []string{"e"},
+		Run: handler(Model.openEditModal)},
+	{Name: "delete", Title: "Delete selection", Help: "Delete", Keys: []string{"d", "delete", "backspace"},
+		Run: do((*Model).deleteSelection)},
+	{Name: "add-linked", Title: "Add a linked node below the selection", Help: "Next", Keys: []string{"A"},
+		Run: do((*Model).addLinked)},
+	{Name: "link", Title: "Connect the selection to…", Help: "Link", Keys: []string{"C"},
+		Run: do((*Model).startPick)},
+	{Name: "pick-connect", Title: "Connect to the picked node", Keys: []string{"enter"},
+		Run: do((*Model).pickConnect)},
+	{Name: "group", Title: "Group selection", Help: "Group", Keys: []string{"G"},
+		Run: do((*Model).groupSelection)},
+	{Name: "collapse", Title: "Collapse or expand group", Help: "Collapse", Keys: []string{"z"},
+		Run: do((*Model).toggleCollapse)},
+	{Name: "raise", Title: "Raise node", Keys: []string{"]"},
+		Run: do(func(m *Model) { m.reorder(m.Graph.Raise) })},
+	{Name: "lower", Title: "Lower node", Keys: []string{"["},
+		Run: do(func(m *Model) { m.reorder(m.Graph.Lower) })},
+	{Name: "front", Title: "Bring node to front", Keys: []string{"}"},
+		Run: do(func(m *Model) { m.reorder(m.Graph.BringToFront) })},
+	{Name: "back", Title: "Send node to back", Keys: []string{"{"},
+		Run: do(func(m *Model) { m.reorder(m.Graph.SendToBack) })},
+	{Name: "edge-line", Title: "Cycle edge line style", Help: "Line", Keys: []string{"w"},
+		Run: do((*Model).cycleEdgeLine)},
+	{Name: "edge-arrow", Title: "Cycle edge arrowhead", Help: "Arrow", Keys: []string{"W"},
+		Run: do((*Model).cycleEdgeArrow)},
+	{Name: "cancel", Title: "Cancel and clear selection", Keys: []string{"esc", "escape"},
+		Run: do((*Model).cancel)},
+
+	// Files
+	{Name: "save", Title: "Save chart", Help: "Save", Keys: []string{"ctrl+s"},
+		Run: do((*Model).save)},
+	{Name: "export-svg", Title: "Export SVG", Help: "SVG", Keys: []string{"E"},
+		Run: do((*Model).exportSVG)},
+	{Name: "quit", Title: "Quit", Help: "Quit", Keys: []string{"q", "ctrl+c"},
+		Run: func(m *Model) tea.Cmd { return tea.Quit }},
+
+	// Interpreter
+	{Name: "run", Title: "Run program", Help: "Run", Keys: []string{"r"},
+		Run: handler(Model.startProgram)},
+	{Name: "step", Title: "Step program", Help: "Step", Keys: []string{"n"},
+		Run: handler(Model.stepProgram)},
+	{Name: "auto", Title: "Auto-run program", Help: "Auto", Keys: []string{"g"},
+		Run: handler(Model.autoRun)},
+	{Name: "pause", Title: "Pause auto-run", Help: "Pause", Keys: []string{"p"},
+		Run: do(func(m *Model) { m.AutoRunning = false })},
+	{Name: "stop", Title: "Stop program", Help: "Stop", Keys: []string{"x"},
+		Run: do((*Model).stopProgram)},
+
+	// Display
+	{Name: "braille", Title: "Toggle Braille edges", Help: "Braille", Keys: []string{"b"},
+		Run: do((*Model).toggleBraille)},
+	{Name: "orthogonal", Title: "Toggle orthogonal routing", Help: "Ortho", Keys: []string{"o"},
+		Run: do(func(m *Model) {
+			m.Orthogonal = !m.Orthogonal
+			m.Status = "routing: " + onOff(m.Orthogonal, "orthogonal", "direct")
+		})},
+	{Name: "hops", Title: "Toggle crossing hops", Help: "Hops", Keys: []string{"O"},
+		Run: do(func(m *Model) {
+			m.Hops = !m.Hops
+			m.Status = "crossings: " + onOff(m.Hops, "hops", "junctions")
+		})},
+	{Name: "shapes", Title: "Toggle node shapes", Help: "Shapes", Keys: []string{"v"},
+		Run: do(func(m *Model) {
+			m.Shapes = !m.Shapes
+			m.Status = "nodes: " + onOff(m.Shapes, "shapes", "boxes")
+		})},
+	{Name: "minimap", Title: "Toggle minimap", Help: "Map", Keys: []string{"M"},
+		Run: do(func(m *Model) {
+			m.Minimap = !m.Minimap
+			m.Status = "minimap: " + onOff(m.Minimap, "on", "off")
+		})},
+
+	// Camera
+	{Name: "zoom-in", Title: "Zoom in", Help: "Zoom+", Keys: []string{"+", "="},
+		Run: do(func(m *Model) { m.zoomTo(m.Zoom-1, m.zoomAnchor()) })},
+	{Name: "zoom-out", Title: "Zoom out", Help: "Zoom-", Keys: []string{"-"},
+		Run: do(func(m *Model) { m.zoomTo(m.Zoom+1, m.zoomAnchor()) })},
+	{Name: "follow", Title: "Toggle following execution", Help: "Follow", Keys: []string{"f"},
+		Run: do(func(m *Model) {
+			m.Follow = !m.Follow
+			m.Status = "follow: " + onOff(m.Follow, "on", "off")
+			m.follow()
+		})},
+	{Name: "fit", Title: "Fit chart", Help: "Fit", Keys: []string{"F"},
+		Run: do((*Model).fitChart)},
+	{Name: "center", Title: "Centre on selection", Help: "Center", Keys: []string{"."},
+		Run: do((*Model).centerSelection)},
+	{Name: "pan-up", Title: "Pan up", Keys: []string{"up"},
+		Run: do(func(m *Model) { m.pan("up") })},
+	{Name: "pan-down", Title: "Pan down", Keys: []string{"down"},
+		Run: do(func(m *Model) { m.pan("down") })},
+	{Name: "pan-left", Title: "Pan left", Keys: []string{"left"},
+		Run: do(func(m *Model) { m.pan("left") })},
+	{Name: "pan-right", Title: "Pan right", Keys: []string{"right"},
+		Run: do(func(m *Model) { m.pan("right") })},
+
+	// Keyboard navigation
+	{Name: "nav-left", Title: "Select node to the left", Keys: []string{"h"},
+		Run: do(func(m *Model) { m.navigate(navDirs["nav-left"]) })},
+	{Name: "nav-down", Title: "Select node below", Keys: []string{"j"},
+		Run: do(func(m *Model) { m.navigate(navDirs["nav-down"]) })},
+	{Name: "nav-up", Title: "Select node above", Keys: []string{"k"},
+		Run: do(func(m *Model) { m.navigate(navDirs["nav-up"]) })},
+	{Name: "nav-right", Title: "Select node to the right", Keys: []string{"l"},
+		Run: do(func(m *Model) { m.navigate(navDirs["nav-right"]) })},
+	{Name: "next-node", Title: "Select next node", Keys: []string{"tab"},
+		Run: do(func(m *Model) { m.tabSelect(false) })},
+	{Name: "prev-node", Title: "Select previous node", Keys: []string{"shift+tab"},
+		Run: do(func(m *Model) { m.tabSelect(true) })},
+	{Name: "move-up", Title: "Move selection up", Keys: []string{"shift+up"},
+		Run: do(func(m *Model) { m.nudge(nudgeDirs["move-up"]) })},
+	{Name: "move-down", Title: "Move selection down", Keys: []string{"shift+down"},
+		Run: do(func(m *Model) { m.nudge(nudgeDirs["move-down"]) })},
+	{Name: "move-left", Title: "Move selection left", Keys: []string{"shift+left"},
+		Run: do(func(m *Model) { m.nudge(nudgeDirs["move-left"]) })},
+	{Name: "move-right", Title: "Move selection right", Keys: []string{"shift+right"},
+		Run: do(func(m *Model) { m.nudge(nudgeDirs["move-right"]) })},
+}
+
+// actionNamed returns the registered action called name, or nil.
+func actionNamed(name string) *Action {
+	for _, a := range actions {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// KeyConfig rebinds actions: each action named in it gets the listed
+// keys instead of its defaults. Keys are written as bubbletea names
+// them, e.g. "ctrl+k", "shift+tab" or "R". In a file it is a JSON object:
+//
+//	{"palette": ["ctrl+k"], "run": ["R"]}
+type KeyConfig map[string][]string
+
+// reservedKeys are handled by the editor itself and cannot be bound:
+// space is held down for space+drag panning.
+var reservedKeys = []string{"space"}
+
+// Keymap maps keys to the actions they run.
+type Keymap map[string]*Action
+
+// NewKeymap binds the registered actions to their default keys, or to
+// the keys cfg gives them. A key bound in cfg is taken away from any
+// action it is a default for. Unknown action names, reserved keys and
+// keys that cfg binds to two actions are errors.
+func NewKeymap(cfg KeyConfig) (Keymap, error) {
+	names := slices.Sorted(maps.Keys(cfg))
+	bound := map[string]string{}
+	for _, name := range names {
+		if actionNamed(name) == nil {
+			return nil, fmt.Errorf("key config: unknown action %q", name)
+		}
+		for _, k := range cfg[name] {
+			if slices.Contains(reservedKeys, k) {
+				return nil, fmt.Errorf("key config: %q is reserved and cannot be bound to %q", k, name)
+			}
+			if other, ok := bound[k]; ok && other != name {
+				return nil, fmt.Errorf("key config: %q is bound to both %q and %q", k, other, name)
+			}
+			bound[k] = name
+		}
+	}
+	km := Keymap{}
+	for _, a := range actions {
+		if _, ok := cfg[a.Name]; ok {
+			continue
+		}
+		for _, k := range a.Keys {
+			km[k] = a
+		}
+	}
+	for k, name := range bound {
+		km[k] = actionNamed(name)
+	}
+	return km, nil
+}
+
+// defaultKeymap binds every action to its default keys.
+var defaultKeymap, _ = NewKeymap(nil)
+
+// KeysFor returns the keys bound to action a, defaults first, then the
+// others in sorted order.
+func (km Keymap) KeysFor(a *Action) []string {
+	var keys []string
+	for _, k := range a.Keys {
+		if km[k] == a {
+			keys = append(keys, k)
+		}
+	}
+	var extra []string
+	for k, b := range km {
+		if b == a && !slices.Contains(a.Keys, k) {
+			extra = append(extra, k)
+		}
+	}
+	slices.Sort(extra)
+	return append(keys, extra...)
+}
+
+// KeyConfigPath returns where the key config file is looked for:
+// grail/keys.json in the user's config directory.
+func KeyConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "grail", "keys.json"), nil
+}
+
+// LoadKeyConfig reads a key config file.
+func LoadKeyConfig(path string) (KeyConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg KeyConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// helpEntries returns the help panel's entries, "[key]Label" for each
+// action with a help label and a key, in registry order.
+func (km Keymap) helpEntries() []string {
+	var entries []string
+	for _, a := range actions {
+		if a.Help == "" {
+			continue
+		}
+		if keys := km.KeysFor(a); len(keys) > 0 {
+			entries = append(entries, "["+keys[0]+"]"+a.Help)
+		}
+	}
+	return entries
+}
+
+// helpHints returns the help panel's hint lines for the mouse and for
+// the actions too many to list one by one: navigation, panning and
+// moving the selection.
+func (km Keymap) helpHints() []string {
+	move := "drag"
+	if keys := km.keysHint("move-up", "move-down", "move-left", "move-right"); keys != "" {
+		move += "/" + keys
+	}
+	last := ""
+	if nav := km.navHint(); nav != "" {
+		last = nav + ": nav"
+	}
+	if keys := km.keysHint("pan-up", "pan-down", "pan-left", "pan-right"); keys != "" {
+		last = strings.TrimLeft(last+"  "+keys+": pan", " ")
+	}
+	return []string{
+		"click=select " + move + "=move",
+		"wheel/mid-drag/space+drag: pan",
+		last,
+	}
+}
+
+// navHint sums up the keys that move between nodes, as in "hjkl/tab".
+func (km Keymap) navHint() string {
+	var hints []string
+	for _, keys := range []string{
+		km.keysHint("nav-left", "nav-down", "nav-up", "nav-right"),
+		km.keysHint("next-node"),
+	} {
+		if keys != "" {
+			hints = append(hints, keys)
+		}
+	}
+	return strings.Join(hints, "/")
+}
+
+// keysHint sums up the first keys bound to the named actions: "arrows"
+// for the four arrow keys, single characters run together as in "hjkl",
+// and a "shift+" they all share written once as "⇧". Unbound actions are
+// left out.
+func (km Keymap) keysHint(names ...string) string {
+	var keys []string
+	for _, name := range names {
+		if k := km.KeysFor(actionNamed(name)); len(k) > 0 {
+			keys = append(keys, k[0])
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	prefix := ""
+	if slices.IndexFunc(keys, func(k string) bool { return !strings.HasPrefix(k, "shift+") }) < 0 {
+		prefix = "⇧"
+		for i, k := range keys {
+			keys[i] = strings.TrimPrefix(k, "shift+")
+		}
+	}
+	sorted := slices.Sorted(slices.Values(keys))
+	switch {
+	case slices.Equal(sorted, []string{"down", "left", "right", "up"}):
+		return prefix + "arrows"
+	case slices.IndexFunc(keys, func(k string) bool { return len([]rune(k)) != 1 }) < 0:
+		return prefix + strings.Join(keys, "")
+	}
+	return prefix + strings.Join(keys, "/")
+}
+
+// wrapEntries packs entries into lines of at most width cells, separated
+// by spaces.
+func wrapEntries(entries []string, width int) []string {
+	var lines []string
+	line := ""
+	for _, e := range entries {
+		switch {
+		case line == "":
+			line = e
+		case len([]rune(line))+1+len([]rune(e)) <= width:
+			line += " " + e
+		default:
+			lines = append(lines, line)
+			line = e
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// setTool switches tools, dropping a half-made connection.
+func (m *Model) setTool(tool Tool) {
+	m.CurrentTool = tool
+	m.ConnectFromID = nil
+}
+
+// setAddType switches to the add tool with node type nt.
+func (m *Model) setAddType(nt string) {
+	m.AddNodeType = nt
+	m.CurrentTool = ToolAdd
+}
+
+// deleteSelection deletes the selected node or edge, or dissolves the
+// selected group (its children are kept).
+func (m *Model) deleteSelection() {
+	if m.SelectedID != nil {
+		m.Graph.RemoveNode(*m.SelectedID)
+		m.SelectedID = nil
+	}
+	if m.SelectedGroupID != nil {
+		m.Graph.RemoveGroup(*m.SelectedGroupID)
+		m.SelectedGroupID = nil
+	}
+	if m.SelectedEdgeID != nil {
+		m.Graph.RemoveEdgeByID(*m.SelectedEdgeID)
+		m.SelectedEdgeID = nil
+	}
+}
+
+// reorder applies a z-order change to the selected node.
+func (m *Model) reorder(f func(id int)) {
+	if m.SelectedID != nil {
+		f(*m.SelectedID)
+	}
+}
+
+// cycleEdgeLine switches the selected edge to the next line style.
+func (m *Model) cycleEdgeLine() {
+	if e := m.selectedEdge(); e != nil {
+		e.Data.Line = nextName(edgeLineNames, e.Data.Line)
+		m.Status = "line: " + nameOr(e.Data.Line, edgeLineNames)
+	}
+}
+
+// cycleEdgeArrow switches the selected edge to the next arrowhead.
+func (m *Model) cycleEdgeArrow() {
+	if e := m.selectedEdge(); e != nil {
+		e.Data.Arrow = nextName(edgeArrowNames, e.Data.Arrow)
+		m.Status = "arrow: " + nameOr(e.Data.Arrow, edgeArrowNames)
+	}
+}
+
+// cancel drops the current operation and the selection.
+func (m *Model) cancel() {
+	m.ConnectFromID = nil
+	m.SelectedID = nil
+	m.SelectedGroupID = nil
+	m.SelectedEdgeID = nil
+	m.CurrentTool = ToolSelect
+}
+
+// keyLabel returns the keys bound to a, for the palette.
+func (km Keymap) keyLabel(a *Action) string {
+	return strings.Join(km.KeysFor(a), " ")
+}
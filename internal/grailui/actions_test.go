@@ -0,0 +1,90 @@
+package grailui
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	for _, tc := range []struct {
+		query, s string
+		ok       bool
+	}{
+		{"", "anything", true},
+		{"exp", "Export SVG", true},
+		{"esvg", "Export SVG", true},
+		{"SVGE", "Export SVG", false},
+		{"tgb", "Toggle Braille edges", true},
+	} {
+		if _, ok := fuzzyMatch(tc.query, tc.s); ok != tc.ok {
+			t.Errorf("fuzzyMatch(%q, %q) ok = %v, want %v", tc.query, tc.s, ok, tc.ok)
+		}
+	}
+
+	// Runs of letters and word starts beat scattered letters
+	run, _ := fuzzyMatch("run", "Run program")
+	scattered, _ := fuzzyMatch("run", "Centre on selection")
+	if run <= scattered {
+		t.Errorf("score for a run of letters %d, want more than scattered %d", run, scattered)
+	}
+}
+
+func TestHelpHints(t *testing.T) {
+	want := []string{
+		"click=select drag/⇧arrows=move",
+		"wheel/mid-drag/space+drag: pan",
+		"hjkl/tab: nav  arrows: pan",
+	}
+	if got := defaultKeymap.helpHints(); !slices.Equal(got, want) {
+		t.Errorf("default hints = %q, want %q", got, want)
+	}
+
+	km, err := NewKeymap(KeyConfig{
+		"nav-left": {"left"}, "nav-down": {"down"}, "nav-up": {"up"}, "nav-right": {"right"},
+		"pan-left": {"ctrl+h"}, "pan-down": {"ctrl+j"}, "pan-up": {"ctrl+k"}, "pan-right": {"ctrl+l"},
+		"next-node": {}, "move-up": {"K"}, "move-down": {"J"}, "move-left": {"H"}, "move-right": {"L"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		"click=select drag/KJHL=move",
+		"wheel/mid-drag/space+drag: pan",
+		"arrows: nav  ctrl+k/ctrl+j/ctrl+h/ctrl+l: pan",
+	}
+	if got := km.helpHints(); !slices.Equal(got, want) {
+		t.Errorf("rebound hints = %q, want %q", got, want)
+	}
+}
+
+func TestNewKeymap(t *testing.T) {
+	km, err := NewKeymap(KeyConfig{"run": {"R", "ctrl+r"}, "palette": {"r"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := km["R"]; a == nil || a.Name != "run" {
+		t.Errorf("R runs %v, want run", a)
+	}
+	if a := km["r"]; a == nil || a.Name != "palette" {
+		t.Errorf("r runs %v, want palette, which took it from run", a)
+	}
+	if a := km[":"]; a != nil {
+		t.Errorf(": runs %s, want nothing once palette is rebound", a.Name)
+	}
+	if got, want := km.KeysFor(actionNamed("run")), []string{"R", "ctrl+r"}; !slices.Equal(got, want) {
+		t.Errorf("keys for run = %v, want %v", got, want)
+	}
+	if a := km["s"]; a == nil || a.Name != "tool-select" {
+		t.Errorf("s runs %v, want the default, tool-select", a)
+	}
+
+	if _, err := NewKeymap(KeyConfig{"no-such-action": {"x"}}); err == nil {
+		t.Error("an unknown action should be an error")
+	}
+	if _, err := NewKeymap(KeyConfig{"run": {"R"}, "stop": {"R"}}); err == nil {
+		t.Error("a key bound to two actions should be an error")
+	}
+	if _, err := NewKeymap(KeyConfig{"fit": {"space"}}); err == nil {
+		t.Error("binding space, which pans while held, should be an error")
+	}
+}
@@ -2,26 +2,33 @@ package grailui
 
 import (
 	"image"
+	"slices"
 
+	"github.com/wesen/grail/pkg/drawutil"
 	"github.com/wesen/grail/pkg/graphmodel"
 )
 
 // NodeTypeInfo describes the fixed geometry and display metadata for a node type.
 type NodeTypeInfo struct {
-	Label string // human-readable label (e.g. "Process")
-	Tag   string // short tag in top border (e.g. "P", "?", "IO")
-	W, H  int    // fixed width and height in terminal cells
+	Label string         // human-readable label (e.g. "Process")
+	Tag   string         // short tag in top border (e.g. "P", "?", "IO")
+	W, H  int            // fixed width and height in terminal cells
+	Shape drawutil.Shape // flowchart symbol, drawn with [v]
+	Glyph rune           // the node at the smallest zoom
 }
 
 // nodeTypeInfo maps node type strings to their geometry.
 var nodeTypeInfo = map[string]NodeTypeInfo{
-	"process":   {Label: "Process", Tag: "P", W: 22, H: 3},
-	"decision":  {Label: "Decision", Tag: "?", W: 22, H: 3},
-	"terminal":  {Label: "Terminal", Tag: "T", W: 22, H: 3},
-	"io":        {Label: "I/O", Tag: "IO", W: 22, H: 3},
-	"connector": {Label: "Connector", Tag: "", W: 7, H: 3},
+	"process":   {Label: "Process", Tag: "P", W: 22, H: 3, Shape: drawutil.ShapeBox, Glyph: '■'},
+	"decision":  {Label: "Decision", Tag: "?", W: 22, H: 3, Shape: drawutil.ShapeDiamond, Glyph: '◆'},
+	"terminal":  {Label: "Terminal", Tag: "T", W: 22, H: 3, Shape: drawutil.ShapeRounded, Glyph: '●'},
+	"io":        {Label: "I/O", Tag: "IO", W: 22, H: 3, Shape: drawutil.ShapeParallelogram, Glyph: '▰'},
+	"connector": {Label: "Connector", Tag: "", W: 7, H: 3, Shape: drawutil.ShapeCircle, Glyph: '○'},
 }
 
+// groupPlaceholder is the size of a collapsed group's placeholder box.
+var groupPlaceholder = image.Pt(22, 3)
+
 // FlowNodeData is the concrete node type stored in the graph.
 type FlowNodeData struct {
 	Type string
@@ -39,23 +46,78 @@ func (n FlowNodeData) Size() image.Point {
 	return image.Pt(info.W, info.H)
 }
 
+// Ports implements graphmodel.Ported. Every node has a port at the
+// middle of each side; a decision's bottom and right ports are its "Y"
+// and "N" branches.
+func (n FlowNodeData) Ports() []graphmodel.Port {
+	info := nodeTypeInfo[n.Type]
+	bottom, right := "bottom", "right"
+	if n.Type == "decision" {
+		bottom, right = "Y", "N"
+	}
+	return []graphmodel.Port{
+		{Name: "top", Offset: image.Pt(info.W/2, 0)},
+		{Name: bottom, Offset: image.Pt(info.W/2, info.H-1)},
+		{Name: "left", Offset: image.Pt(0, info.H/2)},
+		{Name: right, Offset: image.Pt(info.W-1, info.H/2)},
+	}
+}
+
 // SetPos is the setter for graphmodel.MoveNode.
 func SetPos(n *FlowNodeData, p image.Point) {
 	n.X = p.X
 	n.Y = p.Y
 }
 
-// FlowEdgeData holds optional edge metadata.
+// FlowEdgeData holds optional edge metadata. Line and Arrow name the
+// edge's line style and arrowheads (see edgeLineNames and
+// edgeArrowNames); empty names are the defaults, a solid line with a
+// triangle arrowhead.
 type FlowEdgeData struct {
 	Label string
+	Line  string
+	Arrow string
+}
+
+// edgeLineNames and edgeArrowNames name the drawutil line kinds and
+// arrowheads by value, in the order [w] and [W] cycle through them.
+var (
+	edgeLineNames  = []string{"solid", "dashed", "dotted", "thick", "double"}
+	edgeArrowNames = []string{"triangle", "open", "none", "both"}
+)
+
+// stroke returns how the edge is drawn. Unknown names draw the default.
+func (e FlowEdgeData) stroke() drawutil.Stroke {
+	var s drawutil.Stroke
+	if i := slices.Index(edgeLineNames, e.Line); i > 0 {
+		s.Line.Kind = drawutil.LineKind(i)
+	}
+	if i := slices.Index(edgeArrowNames, e.Arrow); i > 0 {
+		s.Arrow = drawutil.Arrow(i)
+	}
+	return s
+}
+
+// nextName returns the name after cur in names, wrapping around, with
+// the first name (the default) returned as "".
+func nextName(names []string, cur string) string {
+	i := (max(slices.Index(names, cur), 0) + 1) % len(names)
+	if i == 0 {
+		return ""
+	}
+	return names[i]
 }
 
 // FlowGraph is the concrete graph type for GRaIL.
 type FlowGraph = graphmodel.Graph[FlowNodeData, FlowEdgeData]
 
-// NewFlowGraph creates an empty flow graph.
+// NewFlowGraph creates an empty flow graph. Parallel edges are allowed so
+// that both branches of a decision can lead to the same node.
 func NewFlowGraph() *FlowGraph {
-	return graphmodel.New[FlowNodeData, FlowEdgeData]()
+	g := graphmodel.New[FlowNodeData, FlowEdgeData]()
+	g.SetMultiEdges(true)
+	g.SetCollapsedSize(groupPlaceholder)
+	return g
 }
 
 // MakeInitialGraph creates the demo flowchart (sum 1..5).
@@ -72,10 +134,10 @@ func MakeInitialGraph() *FlowGraph {
 
 	g.AddEdge(start, init, FlowEdgeData{})
 	g.AddEdge(init, cond, FlowEdgeData{})
-	g.AddEdge(cond, accum, FlowEdgeData{Label: "Y"})
+	g.AddPortEdge(cond, "Y", accum, "top", FlowEdgeData{Label: "Y"})
 	g.AddEdge(accum, conn, FlowEdgeData{})
 	g.AddEdge(conn, cond, FlowEdgeData{})
-	g.AddEdge(cond, printN, FlowEdgeData{Label: "N"})
+	g.AddPortEdge(cond, "N", printN, "left", FlowEdgeData{Label: "N"})
 	g.AddEdge(printN, end, FlowEdgeData{})
 
 	return g
@@ -0,0 +1,128 @@
+package grailui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wesen/grail/pkg/graphmodel"
+)
+
+// FlowDiff is the structural difference between two flow graphs.
+type FlowDiff = graphmodel.GraphDiff[FlowNodeData, FlowEdgeData]
+
+// flowDiffOptions compares everything but position for nodes (position
+// is reported separately as a move) and the label and style for edges.
+var flowDiffOptions = graphmodel.DiffOptions[FlowNodeData, FlowEdgeData]{
+	NodeEqual: func(a, b FlowNodeData) bool {
+		return a.Type == b.Type && a.Text == b.Text && a.Code == b.Code
+	},
+	EdgeEqual: func(a, b FlowEdgeData) bool { return a == b },
+}
+
+// DiffGraphs compares two revisions of a chart.
+func DiffGraphs(a, b *FlowGraph) FlowDiff {
+	return graphmodel.Diff(a, b, flowDiffOptions)
+}
+
+// MergeGraphs merges two revisions of a chart against their common base.
+// See graphmodel.Merge3 for how conflicts are resolved.
+func MergeGraphs(base, ours, theirs *FlowGraph) (*FlowGraph, []graphmodel.Conflict) {
+	return graphmodel.Merge3(base, ours, theirs, graphmodel.MergeOptions[FlowNodeData, FlowEdgeData]{
+		DiffOptions: flowDiffOptions,
+		SetPos:      SetPos,
+	})
+}
+
+// FormatDiff renders a diff as one line per change, prefixed with "+"
+// (added), "-" (removed) or "~" (changed).
+func FormatDiff(d FlowDiff) string {
+	var sb strings.Builder
+	for _, nd := range d.Nodes {
+		switch {
+		case nd.Change.Has(graphmodel.Added):
+			fmt.Fprintf(&sb, "+ node %d %s %q at (%d,%d)\n", nd.ID, nd.New.Type, nd.New.Text, nd.New.X, nd.New.Y)
+		case nd.Change.Has(graphmodel.Removed):
+			fmt.Fprintf(&sb, "- node %d %s %q\n", nd.ID, nd.Old.Type, nd.Old.Text)
+		default:
+			fmt.Fprintf(&sb, "~ node %d %q %s", nd.ID, nd.New.Text, nd.Change)
+			if nd.Change.Has(graphmodel.Moved) {
+				fmt.Fprintf(&sb, " (%d,%d)→(%d,%d)", nd.Old.X, nd.Old.Y, nd.New.X, nd.New.Y)
+			}
+			sb.WriteByte('\n')
+		}
+	}
+	for _, ed := range d.Edges {
+		switch {
+		case ed.Change.Has(graphmodel.Added):
+			fmt.Fprintf(&sb, "+ edge %d %s\n", ed.ID, edgeString(ed.New))
+		case ed.Change.Has(graphmodel.Removed):
+			fmt.Fprintf(&sb, "- edge %d %s\n", ed.ID, edgeString(ed.Old))
+		default:
+			fmt.Fprintf(&sb, "~ edge %d %s: %s → %s\n", ed.ID, ed.Change, edgeString(ed.Old), edgeString(ed.New))
+		}
+	}
+	return sb.String()
+}
+
+// edgeString formats an edge as "from→to" with its ports, label and any
+// line style and arrowheads other than the defaults.
+func edgeString(e graphmodel.Edge[FlowEdgeData]) string {
+	s := fmt.Sprintf("%d%s→%d%s", e.FromID, portSuffix(e.FromPort), e.ToID, portSuffix(e.ToPort))
+	if e.Data.Label != "" {
+		s += fmt.Sprintf(" %q", e.Data.Label)
+	}
+	if e.Data.Line != "" {
+		s += " " + e.Data.Line
+	}
+	if e.Data.Arrow != "" {
+		s += " arrow:" + e.Data.Arrow
+	}
+	return s
+}
+
+func portSuffix(port string) string {
+	if port == "" {
+		return ""
+	}
+	return "." + port
+}
+
+// diffGraph builds the graph shown by the diff view: the new revision
+// plus ghost copies of removed nodes and of removed edges between nodes
+// that are still shown. It returns the change for every node that
+// differs.
+func diffGraph(a, b *FlowGraph, d FlowDiff) (*FlowGraph, map[int]graphmodel.Change) {
+	g, _ := UnmarshalGraph(mustMarshal(b))
+	status := make(map[int]graphmodel.Change)
+	for _, nd := range d.Nodes {
+		status[nd.ID] = nd.Change
+		if nd.Change.Has(graphmodel.Removed) {
+			g.InsertNode(nd.ID, nd.Old)
+		}
+	}
+	for _, ed := range d.Edges {
+		if ed.Change.Has(graphmodel.Removed) && g.Node(ed.Old.FromID) != nil && g.Node(ed.Old.ToID) != nil {
+			g.InsertEdge(ed.Old)
+		}
+	}
+	return g, status
+}
+
+// mustMarshal encodes a graph that is known to be valid.
+func mustMarshal(g *FlowGraph) []byte {
+	data, err := MarshalGraph(g)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// NewDiffModel creates a read-only model that shows b with the nodes
+// that differ from a highlighted: added in green, removed (as ghosts) in
+// red and changed in violet.
+func NewDiffModel(a, b *FlowGraph, title string) Model {
+	m := NewModel()
+	m.Graph, m.DiffStatus = diffGraph(a, b, DiffGraphs(a, b))
+	m.Status = "DIFF " + title
+	return m
+}
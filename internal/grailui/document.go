@@ -0,0 +1,178 @@
+package grailui
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"slices"
+
+	"github.com/wesen/grail/pkg/graphmodel"
+)
+
+// documentVersion is written to every saved chart. Files with a newer
+// version are rejected.
+const documentVersion = 1
+
+// Document is the on-disk JSON form of a flow graph. IDs are stored so
+// that diffs and merges can match elements across revisions; nodes are
+// listed back to front.
+type Document struct {
+	Version int        `json:"version"`
+	Nodes   []DocNode  `json:"nodes"`
+	Edges   []DocEdge  `json:"edges"`
+	Groups  []DocGroup `json:"groups,omitempty"`
+}
+
+// DocNode is a node in a Document.
+type DocNode struct {
+	ID    int    `json:"id"`
+	Type  string `json:"type"`
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Text  string `json:"text,omitempty"`
+	Code  string `json:"code,omitempty"`
+	Group *int   `json:"group,omitempty"`
+}
+
+// DocEdge is an edge in a Document.
+type DocEdge struct {
+	ID       int    `json:"id"`
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	FromPort string `json:"fromPort,omitempty"`
+	ToPort   string `json:"toPort,omitempty"`
+	Label    string `json:"label,omitempty"`
+	Line     string `json:"line,omitempty"`  // see edgeLineNames
+	Arrow    string `json:"arrow,omitempty"` // see edgeArrowNames
+}
+
+// DocGroup is a group in a Document. Groups are listed outermost first.
+type DocGroup struct {
+	ID        int     `json:"id"`
+	Parent    *int    `json:"parent,omitempty"`
+	Label     string  `json:"label"`
+	Collapsed bool    `json:"collapsed,omitempty"`
+	Bounds    *[4]int `json:"bounds,omitempty"` // x, y, w, h when set explicitly
+}
+
+// MarshalGraph encodes a graph as an indented JSON Document.
+func MarshalGraph(g *FlowGraph) ([]byte, error) {
+	doc := Document{Version: documentVersion, Nodes: []DocNode{}, Edges: []DocEdge{}}
+	for _, n := range g.Nodes() {
+		doc.Nodes = append(doc.Nodes, DocNode{
+			ID: n.ID, Type: n.Data.Type, X: n.Data.X, Y: n.Data.Y,
+			Text: n.Data.Text, Code: n.Data.Code,
+			Group: optionalID(g.ParentOf(n.ID)),
+		})
+	}
+	for _, e := range g.Edges() {
+		doc.Edges = append(doc.Edges, DocEdge{
+			ID: e.ID, From: e.FromID, To: e.ToID,
+			FromPort: e.FromPort, ToPort: e.ToPort,
+			Label: e.Data.Label, Line: e.Data.Line, Arrow: e.Data.Arrow,
+		})
+	}
+	for _, grp := range g.Groups() {
+		dg := DocGroup{ID: grp.ID, Parent: optionalID(grp.ParentID), Label: grp.Label, Collapsed: grp.Collapsed}
+		if b := grp.Bounds; !b.Empty() {
+			dg.Bounds = &[4]int{b.Min.X, b.Min.Y, b.Dx(), b.Dy()}
+		}
+		doc.Groups = append(doc.Groups, dg)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// UnmarshalGraph decodes a JSON Document into a new flow graph.
+func UnmarshalGraph(data []byte) (*FlowGraph, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version > documentVersion {
+		return nil, fmt.Errorf("document version %d is newer than supported version %d", doc.Version, documentVersion)
+	}
+
+	g := NewFlowGraph()
+	for _, dg := range doc.Groups {
+		grp := graphmodel.Group{ID: dg.ID, ParentID: idOrNone(dg.Parent), Label: dg.Label, Collapsed: dg.Collapsed}
+		if b := dg.Bounds; b != nil {
+			grp.Bounds = image.Rect(b[0], b[1], b[0]+b[2], b[1]+b[3])
+		}
+		if !g.InsertGroup(grp) {
+			return nil, fmt.Errorf("duplicate group id %d", dg.ID)
+		}
+	}
+	for _, dn := range doc.Nodes {
+		if _, ok := nodeTypeInfo[dn.Type]; !ok {
+			return nil, fmt.Errorf("node %d: unknown type %q", dn.ID, dn.Type)
+		}
+		data := FlowNodeData{Type: dn.Type, X: dn.X, Y: dn.Y, Text: dn.Text, Code: dn.Code}
+		if !g.InsertNode(dn.ID, data) {
+			return nil, fmt.Errorf("duplicate node id %d", dn.ID)
+		}
+		g.SetParent(dn.ID, idOrNone(dn.Group))
+	}
+	for _, de := range doc.Edges {
+		if g.Node(de.From) == nil || g.Node(de.To) == nil {
+			return nil, fmt.Errorf("edge %d: unknown endpoint", de.ID)
+		}
+		if de.Line != "" && !slices.Contains(edgeLineNames, de.Line) {
+			return nil, fmt.Errorf("edge %d: unknown line style %q", de.ID, de.Line)
+		}
+		if de.Arrow != "" && !slices.Contains(edgeArrowNames, de.Arrow) {
+			return nil, fmt.Errorf("edge %d: unknown arrowhead %q", de.ID, de.Arrow)
+		}
+		e := graphmodel.Edge[FlowEdgeData]{
+			ID: de.ID, FromID: de.From, ToID: de.To,
+			FromPort: de.FromPort, ToPort: de.ToPort,
+			Data: FlowEdgeData{Label: de.Label, Line: de.Line, Arrow: de.Arrow},
+		}
+		if !g.InsertEdge(e) {
+			return nil, fmt.Errorf("duplicate edge id %d", de.ID)
+		}
+	}
+	return g, nil
+}
+
+// LoadGraph reads a chart file.
+func LoadGraph(path string) (*FlowGraph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	g, err := UnmarshalGraph(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return g, nil
+}
+
+// SaveGraph writes a chart file.
+func SaveGraph(path string, g *FlowGraph) error {
+	data, err := MarshalGraph(g)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// optionalID maps NoGroup to nil for omitempty encoding.
+func optionalID(id int) *int {
+	if id == graphmodel.NoGroup {
+		return nil
+	}
+	return &id
+}
+
+// idOrNone is the inverse of optionalID.
+func idOrNone(id *int) int {
+	if id == nil {
+		return graphmodel.NoGroup
+	}
+	return *id
+}
@@ -0,0 +1,89 @@
+package grailui
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/wesen/grail/pkg/graphmodel"
+)
+
+// loopGraph returns the demo chart with its loop — the decision,
+// ACCUMULATE and the connector — in a group called LOOP, group 0.
+func loopGraph() *FlowGraph {
+	g := MakeInitialGraph()
+	loop := g.AddGroup("LOOP", graphmodel.NoGroup)
+	for _, id := range []int{2, 3, 4} {
+		g.SetParent(id, loop)
+	}
+	return g
+}
+
+func TestDocumentRoundTrip(t *testing.T) {
+	g := loopGraph()
+	g.Group(0).Collapsed = true
+	data, err := MarshalGraph(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2, err := UnmarshalGraph(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := DiffGraphs(g, g2); !d.Empty() {
+		t.Errorf("round trip changed the graph:\n%s", FormatDiff(d))
+	}
+	if !g2.Group(0).Collapsed || g2.ParentOf(2) != 0 {
+		t.Error("round trip lost group state")
+	}
+	data2, _ := MarshalGraph(g2)
+	if !bytes.Equal(data, data2) {
+		t.Error("re-encoding a loaded graph should be byte-identical")
+	}
+}
+
+func TestUnmarshalRejectsBadEdges(t *testing.T) {
+	doc := `{"version":1,"nodes":[{"id":0,"type":"process","x":0,"y":0}],"edges":[{"id":0,"from":0,"to":7}]}`
+	if _, err := UnmarshalGraph([]byte(doc)); err == nil {
+		t.Error("expected an error for an edge to an unknown node")
+	}
+}
+
+func TestDocumentEdgeStyles(t *testing.T) {
+	g := MakeInitialGraph()
+	g.Edge(0).Data.Line = "double"
+	g.Edge(0).Data.Arrow = "both"
+	data, err := MarshalGraph(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2, err := UnmarshalGraph(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := g2.Edge(0).Data; e.Line != "double" || e.Arrow != "both" {
+		t.Errorf("round trip lost edge style: %+v", e)
+	}
+
+	doc := `{"version":1,"nodes":[{"id":0,"type":"process","x":0,"y":0}],"edges":[{"id":0,"from":0,"to":0,"line":"wavy"}]}`
+	if _, err := UnmarshalGraph([]byte(doc)); err == nil {
+		t.Error("expected an error for an unknown line style")
+	}
+}
+
+func TestDiffViewGhosts(t *testing.T) {
+	a := MakeInitialGraph()
+	b := MakeInitialGraph()
+	b.RemoveNode(6) // END
+	b.Node(0).Data.Text = "BEGIN"
+
+	g, status := diffGraph(a, b, DiffGraphs(a, b))
+	if g.Node(6) == nil {
+		t.Fatal("removed node should be shown as a ghost")
+	}
+	if !g.HasEdge(5, 6) {
+		t.Error("removed edge between shown nodes should be restored")
+	}
+	if !status[6].Has(graphmodel.Removed) || !status[0].Has(graphmodel.Modified) {
+		t.Errorf("unexpected status map %v", status)
+	}
+}
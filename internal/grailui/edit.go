@@ -7,6 +7,7 @@ import (
 	tea "charm.land/bubbletea/v2"
 	"charm.land/bubbles/v2/textinput"
 	"charm.land/lipgloss/v2"
+	"github.com/wesen/grail/pkg/graphmodel"
 )
 
 // codeHints provides input hints per node type.
@@ -16,8 +17,12 @@ var codeHints = map[string]string{
 	"io":       ` (print("..") or input("prompt", var))`,
 }
 
-// openEditModal opens the edit modal for the selected node.
+// openEditModal opens the edit modal for the selected node, or for the
+// selected group's label.
 func (m Model) openEditModal() (tea.Model, tea.Cmd) {
+	if m.SelectedGroupID != nil {
+		return m.openGroupEditModal()
+	}
 	if m.SelectedID == nil {
 		return m, nil
 	}
@@ -28,6 +33,7 @@ func (m Model) openEditModal() (tea.Model, tea.Cmd) {
 
 	m.EditOpen = true
 	m.EditNodeID = *m.SelectedID
+	m.EditGroupID = nil
 	m.EditFocus = 0
 
 	m.EditLabel = textinput.New()
@@ -44,6 +50,27 @@ func (m Model) openEditModal() (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// openGroupEditModal opens the edit modal with only a label field.
+func (m Model) openGroupEditModal() (tea.Model, tea.Cmd) {
+	grp := m.Graph.Group(*m.SelectedGroupID)
+	if grp == nil {
+		return m, nil
+	}
+
+	groupID := grp.ID
+	m.EditOpen = true
+	m.EditGroupID = &groupID
+	m.EditFocus = 0
+
+	m.EditLabel = textinput.New()
+	m.EditLabel.Prompt = ""
+	m.EditLabel.CharLimit = 30
+	m.EditLabel.SetValue(grp.Label)
+
+	cmd := m.EditLabel.Focus()
+	return m, cmd
+}
+
 // handleEditKeys processes keys when the edit modal is open.
 func (m Model) handleEditKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	key := msg.String()
@@ -54,6 +81,13 @@ func (m Model) handleEditKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "enter":
 		// Save and close
+		if m.EditGroupID != nil {
+			if grp := m.Graph.Group(*m.EditGroupID); grp != nil {
+				grp.Label = strings.ToUpper(strings.TrimSpace(m.EditLabel.Value()))
+			}
+			m.EditOpen = false
+			return m, nil
+		}
 		node := m.Graph.Node(m.EditNodeID)
 		if node != nil {
 			node.Data.Text = strings.ToUpper(strings.TrimSpace(m.EditLabel.Value()))
@@ -63,7 +97,10 @@ func (m Model) handleEditKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case "tab", "shift+tab":
-		// Toggle focus
+		// Toggle focus (groups only have a label)
+		if m.EditGroupID != nil {
+			return m, nil
+		}
 		if m.EditFocus == 0 {
 			m.EditFocus = 1
 			m.EditLabel.Blur()
@@ -90,13 +127,15 @@ func (m Model) handleEditKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 // buildEditModalLayer renders the edit modal as a centered Z=100 Layer.
 func buildEditModalLayer(m Model, screenW, screenH int) *lipgloss.Layer {
-	node := m.Graph.Node(m.EditNodeID)
-	if node == nil {
+	var node *graphmodel.Node[FlowNodeData]
+	if m.EditGroupID != nil {
+		if m.Graph.Group(*m.EditGroupID) == nil {
+			return nil
+		}
+	} else if node = m.Graph.Node(m.EditNodeID); node == nil {
 		return nil
 	}
 
-	info := nodeTypeInfo[node.Data.Type]
-
 	titleStyle := lipgloss.NewStyle().
 		Foreground(c("#00ffc8")).
 		Background(c("#0a1510")).
@@ -120,18 +159,30 @@ func buildEditModalLayer(m Model, screenW, screenH int) *lipgloss.Layer {
 		focusCode = "▸ "
 	}
 
-	hint := codeHints[node.Data.Type]
-
-	lines := []string{
-		titleStyle.Render(fmt.Sprintf("  ✏️  EDIT — %s", strings.ToUpper(info.Label))),
-		"",
-		labelStyle.Render(focusLabel + "Label:"),
-		"  " + m.EditLabel.View(),
-		"",
-		labelStyle.Render(focusCode + "Code" + hint + ":"),
-		"  " + m.EditCode.View(),
-		"",
-		hintStyle.Render("  [tab] switch  [enter] save  [esc] cancel"),
+	var lines []string
+	if node == nil {
+		lines = []string{
+			titleStyle.Render("  ✏️  EDIT — GROUP"),
+			"",
+			labelStyle.Render(focusLabel + "Label:"),
+			"  " + m.EditLabel.View(),
+			"",
+			hintStyle.Render("  [enter] save  [esc] cancel"),
+		}
+	} else {
+		info := nodeTypeInfo[node.Data.Type]
+		hint := codeHints[node.Data.Type]
+		lines = []string{
+			titleStyle.Render(fmt.Sprintf("  ✏️  EDIT — %s", strings.ToUpper(info.Label))),
+			"",
+			labelStyle.Render(focusLabel + "Label:"),
+			"  " + m.EditLabel.View(),
+			"",
+			labelStyle.Render(focusCode + "Code" + hint + ":"),
+			"  " + m.EditCode.View(),
+			"",
+			hintStyle.Render("  [tab] switch  [enter] save  [esc] cancel"),
+		}
 	}
 
 	content := strings.Join(lines, "\n")
@@ -0,0 +1,94 @@
+package grailui
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"slices"
+
+	"charm.land/lipgloss/v2"
+	"github.com/wesen/grail/pkg/cellbuf"
+	"github.com/wesen/grail/pkg/graphmodel"
+)
+
+// ExportFormats lists the formats accepted by ExportChart.
+var ExportFormats = []string{"txt", "ansi", "html", "svg"}
+
+// exportMargin is the blank border, in cells, around an exported chart.
+const exportMargin = 2
+
+// ExportChart writes the whole chart in the given format, sized to fit
+// every visible node and group. The cell formats are composed from the
+// same layers as the editor canvas — grid, group frames, edges, nodes and
+// edge labels; svg draws the same geometry as vector shapes.
+func ExportChart(w io.Writer, g *FlowGraph, format, title string) error {
+	if !slices.Contains(ExportFormats, format) {
+		return fmt.Errorf("unknown format %q (want one of %v)", format, ExportFormats)
+	}
+	if format == "svg" {
+		return writeSVG(w, g, title)
+	}
+	buf := chartBuffer(g)
+	switch format {
+	case "ansi":
+		return buf.WriteANSI(w, bufStyles)
+	case "html":
+		return buf.WriteHTML(w, bufStyles, title)
+	}
+	return buf.WriteText(w)
+}
+
+// chartBuffer composes the chart into a buffer: edges are drawn into it
+// directly, and the lipgloss-rendered node and label layers are parsed
+// into it in Z order.
+func chartBuffer(g *FlowGraph) *cellbuf.Buffer {
+	world := chartBounds(g).Inset(-exportMargin)
+	viewport := image.Rect(0, 0, world.Dx(), world.Dy())
+	cam := camera{Pos: world.Min, Scale: 1}
+
+	buf := cellbuf.New(viewport.Dx(), viewport.Dy(), styleBG)
+	buildEdgeCanvasLayer(buf, g, cam, viewport, nil, nil, "", nil, nil, 0, 0, edgeStyle{})
+
+	layers := buildNodeLayers(g, cam, viewport, nil, nil, nil, nil, false)
+	layers = append(layers, buildEdgeLabelLayers(g, cam, viewport, false)...)
+	slices.SortStableFunc(layers, func(a, b *lipgloss.Layer) int { return a.GetZ() - b.GetZ() })
+	for _, l := range layers {
+		buf.SetANSI(l.GetX(), l.GetY(), l.GetContent(), styleBG)
+	}
+	return buf
+}
+
+// chartBounds returns the world rectangle covering every visible node,
+// group and edge, including back-edges routed beside the chart. An empty
+// chart has a small non-empty rectangle at the origin.
+func chartBounds(g *FlowGraph) image.Rectangle {
+	r := nodesBounds(g)
+	router := newEdgeRouter(g)
+	for _, edge := range g.Edges() {
+		route, _ := router.route(edge, false)
+		for _, p := range route {
+			r = r.Union(image.Rectangle{p, p.Add(image.Pt(1, 1))})
+		}
+	}
+	if r.Empty() {
+		return image.Rect(0, 0, 1, 1)
+	}
+	return r
+}
+
+// nodesBounds returns the world rectangle covering every visible node and
+// group, or an empty one.
+func nodesBounds(g *FlowGraph) image.Rectangle {
+	var r image.Rectangle
+	for _, n := range g.Nodes() {
+		if !g.Hidden(n.ID) {
+			r = r.Union(graphmodel.BoundsOf(n.Data))
+		}
+	}
+	for _, grp := range g.Groups() {
+		if !g.GroupHidden(grp.ID) {
+			r = r.Union(g.GroupBounds(grp.ID))
+		}
+	}
+	return r
+}
@@ -0,0 +1,91 @@
+package grailui
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportChartText(t *testing.T) {
+	var out bytes.Buffer
+	if err := ExportChart(&out, loopGraph(), "txt", "demo"); err != nil {
+		t.Fatal(err)
+	}
+	s := out.String()
+	for _, want := range []string{"START", "PRINT SUM", "LOOP", "[?]"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("export missing %q:\n%s", want, s)
+		}
+	}
+	for i, line := range strings.Split(s, "\n") {
+		if strings.HasSuffix(line, " ") {
+			t.Errorf("line %d has trailing whitespace: %q", i, line)
+		}
+	}
+}
+
+func TestExportChartSVG(t *testing.T) {
+	var out bytes.Buffer
+	if err := ExportChart(&out, loopGraph(), "svg", "demo <1>"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Count elements, which also checks that the output is well-formed.
+	count := make(map[string]int)
+	dec := xml.NewDecoder(bytes.NewReader(out.Bytes()))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid SVG: %v\n%s", err, out.String())
+		}
+		if el, ok := tok.(xml.StartElement); ok {
+			count[el.Name.Local]++
+		}
+	}
+	// 2 terminals + 2 processes + group frame; decision + io; connector
+	want := map[string]int{"rect": 6, "polygon": 2, "circle": 1, "polyline": 7}
+	for name, n := range want {
+		if count[name] != n {
+			t.Errorf("%d <%s> elements, want %d", count[name], name, n)
+		}
+	}
+	if s := out.String(); !strings.Contains(s, ">PRINT SUM<") || !strings.Contains(s, "demo &lt;1&gt;") {
+		t.Errorf("SVG is missing node text or the escaped title:\n%s", s)
+	}
+}
+
+func TestExportChartUnknownFormat(t *testing.T) {
+	var out bytes.Buffer
+	if err := ExportChart(&out, MakeInitialGraph(), "pdf", ""); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if out.Len() > 0 {
+		t.Errorf("an unknown format should write nothing, wrote %d bytes", out.Len())
+	}
+}
+
+func TestExportSVGKeepsChart(t *testing.T) {
+	// A chart saved as .svg must not be overwritten by its export
+	path := filepath.Join(t.TempDir(), "chart.svg")
+	m := NewModel()
+	m.Path = path
+	m.save()
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.exportSVG()
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, saved) {
+		t.Error("exporting overwrote the chart file")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), "chart.export.svg")); err != nil {
+		t.Errorf("export not written beside the chart: %v", err)
+	}
+}
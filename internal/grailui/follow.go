@@ -0,0 +1,114 @@
+package grailui
+
+import (
+	"image"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+// camTickInterval is the time between frames of a camera pan.
+const camTickInterval = 30 * time.Millisecond
+
+// followMargin is how close, in canvas cells, the executing node may
+// come to the edge of the canvas before follow mode brings it back to
+// the middle.
+const followMargin = 2
+
+// CamTickMsg moves the camera one step toward its target.
+type CamTickMsg time.Time
+
+func camTickCmd() tea.Cmd {
+	return tea.Tick(camTickInterval, func(t time.Time) tea.Msg {
+		return CamTickMsg(t)
+	})
+}
+
+// camFor returns the camera position that puts world point p in the
+// middle of the canvas.
+func (m Model) camFor(p image.Point) image.Point {
+	s := zoomScales[m.Zoom]
+	canvas := m.canvasRect()
+	return p.Sub(image.Pt(canvas.Dx()/2*s, canvas.Dy()/2*s))
+}
+
+// panTo starts a smooth pan that brings world point p to the middle of
+// the canvas.
+func (m *Model) panTo(p image.Point) {
+	target := m.camFor(p)
+	m.CamTarget = &target
+}
+
+// stepCamera moves the camera part of the way to its target, at least a
+// cell at a time, and reports whether it is still short of it.
+func (m *Model) stepCamera() bool {
+	if m.CamTarget == nil {
+		return false
+	}
+	step := func(from, to int) int {
+		d := to - from
+		switch {
+		case d > 0:
+			return from + (d+1)/2
+		case d < 0:
+			return from + (d-1)/2
+		}
+		return from
+	}
+	m.CamX = step(m.CamX, m.CamTarget.X)
+	m.CamY = step(m.CamY, m.CamTarget.Y)
+	if image.Pt(m.CamX, m.CamY) == *m.CamTarget {
+		m.CamTarget = nil
+		return false
+	}
+	return true
+}
+
+// follow pans to the executing node when follow mode is on and the node,
+// or the collapsed group hiding it, is not well inside the canvas.
+func (m *Model) follow() {
+	if !m.Follow || m.ExecID == nil {
+		return
+	}
+	r, _, ok := endpointBounds(m.Graph, *m.ExecID)
+	if !ok {
+		return
+	}
+	m.reveal(r)
+}
+
+// reveal pans to world rectangle r unless it is already well inside the
+// canvas.
+func (m *Model) reveal(r image.Rectangle) {
+	view := m.viewportWorld().Inset(followMargin * zoomScales[m.Zoom])
+	if !r.In(view) {
+		m.panTo(rectCenter(r))
+	}
+}
+
+// fitChart sets the zoom to the most detailed level at which the whole
+// chart fits on the canvas, or the least detailed one if it fits at
+// none, and centres the chart.
+func (m *Model) fitChart() {
+	bounds := chartBounds(m.Graph).Inset(-followMargin)
+	canvas := m.canvasRect()
+	m.Zoom = len(zoomScales) - 1
+	for level, s := range zoomScales {
+		if bounds.Dx() <= canvas.Dx()*s && bounds.Dy() <= canvas.Dy()*s {
+			m.Zoom = level
+			break
+		}
+	}
+	m.centerOn(rectCenter(bounds))
+	m.Status = "fit: zoom " + zoomNames[m.Zoom]
+}
+
+// centerSelection pans to the selected node, group or edge.
+func (m *Model) centerSelection() {
+	p, ok := m.selectionCenter()
+	if !ok {
+		m.Status = "nothing selected"
+		return
+	}
+	m.panTo(p)
+}
@@ -3,9 +3,11 @@ package grailui
 import (
 	"fmt"
 	"image"
-	"math"
+	"image/color"
+	"slices"
 
 	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/wesen/grail/pkg/cellbuf"
 	"github.com/wesen/grail/pkg/drawutil"
 	"github.com/wesen/grail/pkg/graphmodel"
@@ -17,20 +19,36 @@ const (
 	styleGrid       cellbuf.StyleKey = 1
 	styleEdge       cellbuf.StyleKey = 2
 	styleEdgeActive cellbuf.StyleKey = 3
+	styleGroup      cellbuf.StyleKey = 4
+	styleGroupTitle cellbuf.StyleKey = 5
+	styleGroupSel   cellbuf.StyleKey = 6
+	styleEdgeSel    cellbuf.StyleKey = 7
 )
 
 // bufStyles maps cellbuf StyleKeys to lipgloss styles for rendering.
 var bufStyles = map[cellbuf.StyleKey]lipgloss.Style{
 	styleBG:         lipgloss.NewStyle().Foreground(c("#1a3a2a")).Background(c("#080e0b")),
 	styleGrid:       lipgloss.NewStyle().Foreground(c("#0e2e20")).Background(c("#080e0b")),
-	styleEdge:       lipgloss.NewStyle().Foreground(c("#00d4a0")).Background(c("#080e0b")),
-	styleEdgeActive: lipgloss.NewStyle().Foreground(c("#ffcc00")).Background(c("#080e0b")).Bold(true),
+	styleEdge:       lipgloss.NewStyle().Foreground(edgeColor).Background(c("#080e0b")),
+	styleEdgeActive: lipgloss.NewStyle().Foreground(edgeActColor).Background(c("#080e0b")).Bold(true),
+	styleGroup:      lipgloss.NewStyle().Foreground(groupBorder).Background(c("#080e0b")),
+	styleGroupTitle: lipgloss.NewStyle().Foreground(groupTitle).Background(c("#080e0b")).Bold(true),
+	styleGroupSel:   lipgloss.NewStyle().Foreground(selBorder).Background(c("#080e0b")).Bold(true),
+	styleEdgeSel:    lipgloss.NewStyle().Foreground(selBorder).Background(c("#080e0b")).Bold(true),
 }
 
-// buildEdgeCanvasLayer renders the grid + edge lines + connect preview into
-// a cellbuf and returns it as a single background Layer at Z=0.
-func buildEdgeCanvasLayer(g *FlowGraph, camX, camY int, viewport image.Rectangle,
-	execID *int, connectFromID *int, mouseX, mouseY int) *lipgloss.Layer {
+// buildEdgeCanvasLayer renders the grid + group frames + edge lines +
+// connect preview into a cellbuf and returns it as a single background
+// Layer at Z=0. buf is kept between frames so that rows which come out
+// the same are not rendered again; it is resized to the viewport as
+// needed, and a nil buf draws into a fresh buffer. cam maps the world
+// onto the viewport; zoomed out, frames and edge routes are scaled down
+// with it. style selects how edges are routed and drawn; each edge's own
+// line style and arrowheads apply to character lines, while Braille
+// lines are all alike.
+func buildEdgeCanvasLayer(buf *cellbuf.Buffer, g *FlowGraph, cam camera, viewport image.Rectangle,
+	execID *int, connectFromID *int, connectFromPort string, selectedGroupID, selectedEdgeID *int, mouseX, mouseY int,
+	style edgeStyle) *lipgloss.Layer {
 
 	w := viewport.Dx()
 	h := viewport.Dy()
@@ -38,49 +56,76 @@ func buildEdgeCanvasLayer(g *FlowGraph, camX, camY int, viewport image.Rectangle
 		return lipgloss.NewLayer("").X(viewport.Min.X).Y(viewport.Min.Y).Z(0)
 	}
 
-	buf := cellbuf.New(w, h, styleBG)
+	switch {
+	case buf == nil:
+		buf = cellbuf.New(w, h, styleBG)
+	case buf.W != w || buf.H != h:
+		buf.Resize(w, h, styleBG)
+		buf.Fill(styleBG)
+	default:
+		buf.Fill(styleBG)
+	}
+
+	// Grid dots, spaced on the canvas rather than the world
+	origin := cam.toCanvas(image.Point{})
+	drawutil.DrawGrid(buf, -origin.X, -origin.Y, 5, 3, styleGrid)
 
-	// Grid dots
-	drawutil.DrawGrid(buf, camX, camY, 5, 3, styleGrid)
+	// Group frames, outermost first (collapsed groups are node layers)
+	for _, grp := range g.Groups() {
+		if grp.Collapsed || g.GroupHidden(grp.ID) {
+			continue
+		}
+		r := cam.rect(g.GroupBounds(grp.ID))
+		fs := styleGroup
+		if selectedGroupID != nil && grp.ID == *selectedGroupID {
+			fs = styleGroupSel
+		}
+		drawGroupFrame(buf, r, grp.Label, fs, styleGroupTitle)
+	}
 
 	// Edge lines
+	router := newEdgeRouter(g)
 	for _, edge := range g.Edges() {
-		fromNode := g.Node(edge.FromID)
-		toNode := g.Node(edge.ToID)
-		if fromNode == nil || toNode == nil {
+		route, ok := router.route(edge, style.Orthogonal)
+		if !ok {
 			continue
 		}
 
-		fromBounds := graphmodel.BoundsOf(fromNode.Data)
-		toBounds := graphmodel.BoundsOf(toNode.Data)
-		fromCenter := graphmodel.CenterOf(toNode.Data)
-		toCenter := graphmodel.CenterOf(fromNode.Data)
-
-		p1 := drawutil.EdgeExit(fromBounds, fromCenter)
-		p2 := drawutil.EdgeExit(toBounds, toCenter)
-
 		// World → buffer coords
-		bx1 := p1.X - camX
-		by1 := p1.Y - camY
-		bx2 := p2.X - camX
-		by2 := p2.Y - camY
+		route = cam.route(route)
+		if len(route) < 2 {
+			continue
+		}
 
 		// Style: active if executing node is the destination
 		es := styleEdge
 		if execID != nil && edge.ToID == *execID {
 			es = styleEdgeActive
 		}
+		if selectedEdgeID != nil && edge.ID == *selectedEdgeID {
+			es = styleEdgeSel
+		}
 
-		drawutil.DrawArrowLine(buf, bx1, by1, bx2, by2, es, es)
+		if style.Mode == EdgeBraille {
+			drawutil.DrawBrailleArrowPolyline(buf, route, es, es)
+		} else {
+			stroke := edge.Data.stroke()
+			stroke.Crossing = style.Crossing
+			drawutil.DrawStroke(buf, route, stroke, es, es)
+		}
 	}
 
-	// Connect preview: dashed line from source node center to mouse cursor
+	// Connect preview: dashed line from the source port (or node center)
+	// to mouse cursor
 	if connectFromID != nil {
 		node := g.Node(*connectFromID)
 		if node != nil {
-			center := graphmodel.CenterOf(node.Data)
-			sx := center.X - camX
-			sy := center.Y - camY
+			start, ok := graphmodel.PortPos(node.Data, connectFromPort)
+			if !ok {
+				start = graphmodel.CenterOf(node.Data)
+			}
+			s := cam.toCanvas(start)
+			sx, sy := s.X, s.Y
 			tx := mouseX - viewport.Min.X
 			ty := mouseY - viewport.Min.Y
 			drawutil.DrawDashedLine(buf, sx, sy, tx, ty, styleEdgeActive)
@@ -91,63 +136,373 @@ func buildEdgeCanvasLayer(g *FlowGraph, camX, camY int, viewport image.Rectangle
 	return lipgloss.NewLayer(rendered).X(viewport.Min.X).Y(viewport.Min.Y).Z(0).ID("edge-canvas")
 }
 
+// edgeAt returns the ID of the topmost edge whose drawn line passes
+// through canvas cell p, routed and scaled as the canvas draws it.
+func edgeAt(g *FlowGraph, cam camera, p image.Point, orthogonal bool) (id int, ok bool) {
+	router := newEdgeRouter(g)
+	edges := g.Edges()
+	for i := len(edges) - 1; i >= 0; i-- {
+		route, ok := router.route(edges[i], orthogonal)
+		if ok && slices.Contains(drawutil.PolylinePoints(cam.route(route)), p) {
+			return edges[i].ID, true
+		}
+	}
+	return 0, false
+}
+
+// edgeStyle is how the edge canvas routes and draws edges.
+type edgeStyle struct {
+	Mode       EdgeMode
+	Orthogonal bool              // horizontal and vertical legs only
+	Crossing   drawutil.Crossing // how straight crossings are shown
+}
+
+// edgeRouter routes the edges of a graph. It finds the back-edges and
+// their rails once (see newEdgeRouter), so that routing every edge of a
+// chart does not search the graph once per edge; it must not outlive
+// changes to the graph.
+type edgeRouter struct {
+	g     *FlowGraph
+	rails map[int]int // x of each back-edge's rail, by edge ID
+}
+
+// Spacing of back-edge rails: the first runs railGap cells left of the
+// chart and each further one railGap cells beyond the last.
+const railGap = 2
+
+// newEdgeRouter returns a router for g. Each back-edge other than a
+// self-loop gets a rail of its own left of every node and group frame,
+// in edge order.
+func newEdgeRouter(g *FlowGraph) edgeRouter {
+	back := g.BackEdges()
+	rails := make(map[int]int)
+	left := nodesBounds(g).Min.X
+	for _, e := range g.Edges() {
+		if back[e.ID] && e.FromID != e.ToID {
+			rails[e.ID] = left - railGap*(len(rails)+1)
+		}
+	}
+	return edgeRouter{g: g, rails: rails}
+}
+
+// edgeRoute routes a single edge of g; see edgeRouter.route. Use an
+// edgeRouter to route more than one.
+func edgeRoute(g *FlowGraph, edge graphmodel.Edge[FlowEdgeData], orthogonal bool) ([]image.Point, bool) {
+	return newEdgeRouter(g).route(edge, orthogonal)
+}
+
+// route returns the world-space vertices of an edge, from source to
+// target, made of horizontal and vertical legs when orthogonal is set;
+// see directRoute. Self-loops are hooks on the node (see loopRoute) and
+// back-edges, which close a cycle, go around the chart (see railRoute).
+func (r edgeRouter) route(edge graphmodel.Edge[FlowEdgeData], orthogonal bool) ([]image.Point, bool) {
+	g := r.g
+	if edge.FromID == edge.ToID {
+		return loopRoute(g, edge)
+	}
+	if rail, ok := r.rails[edge.ID]; ok {
+		return railRoute(g, edge, rail)
+	}
+	route, ok := directRoute(g, edge)
+	if !ok || !orthogonal {
+		return route, ok
+	}
+	from, _, _ := endpointBounds(g, edge.FromID)
+	to, _, _ := endpointBounds(g, edge.ToID)
+	return drawutil.OrthogonalRoute(route, from, to), true
+}
+
+// directRoute returns the world-space vertices of an edge, from source to
+// target. An end attached to a named port starts (or finishes) at that
+// port and passes through its stub, so the edge leaves perpendicular to
+// the port's side; other ends exit each node's border toward the other
+// node's center. Parallel edges between the same pair of nodes are
+// shifted sideways so that each one stays visible. ok is false if either
+// endpoint node is missing.
+//
+// Edges touching a node hidden in a collapsed group attach to the group's
+// placeholder instead; edges inside one placeholder are not drawn.
+func directRoute(g *FlowGraph, edge graphmodel.Edge[FlowEdgeData]) (route []image.Point, ok bool) {
+	fromBounds, fromGroup, ok1 := endpointBounds(g, edge.FromID)
+	toBounds, toGroup, ok2 := endpointBounds(g, edge.ToID)
+	if !ok1 || !ok2 {
+		return nil, false
+	}
+	if fromGroup != graphmodel.NoGroup && fromGroup == toGroup {
+		return nil, false
+	}
+
+	fromPort, fromHasPort := portPos(g, edge.FromID, edge.FromPort, fromGroup)
+	toPort, toHasPort := portPos(g, edge.ToID, edge.ToPort, toGroup)
+
+	// Aim the free ends at the other end's port when it has one
+	fromAim, toAim := rectCenter(toBounds), rectCenter(fromBounds)
+	if toHasPort {
+		fromAim = toPort
+	}
+	if fromHasPort {
+		toAim = fromPort
+	}
+
+	p1 := drawutil.ShapeExit(fromBounds, endpointShape(g, edge.FromID, fromGroup), fromAim)
+	p2 := drawutil.ShapeExit(toBounds, endpointShape(g, edge.ToID, toGroup), toAim)
+	if !fromHasPort && !toHasPort {
+		p1, p2 = shiftParallel(g, edge.ID, p1, p2)
+		return []image.Point{p1, p2}, true
+	}
+
+	if fromHasPort {
+		route = append(route, fromPort, drawutil.PortStub(fromBounds, fromPort))
+	} else {
+		route = append(route, p1)
+	}
+	if toHasPort {
+		route = append(route, drawutil.PortStub(toBounds, toPort), toPort)
+	} else {
+		route = append(route, p2)
+	}
+	return route, true
+}
+
+// loopRoute returns the route of a self-loop: a hook on the right side
+// of the node, or on the side of its source port if it has one. Loops on
+// a node hidden in a collapsed group are not drawn.
+func loopRoute(g *FlowGraph, edge graphmodel.Edge[FlowEdgeData]) ([]image.Point, bool) {
+	bounds, group, ok := endpointBounds(g, edge.FromID)
+	if !ok || group != graphmodel.NoGroup {
+		return nil, false
+	}
+	side := drawutil.SideRight
+	if p, ok := portPos(g, edge.FromID, edge.FromPort, group); ok {
+		side = drawutil.SideOf(bounds, p)
+	}
+	return drawutil.SelfLoopRoute(bounds, side), true
+}
+
+// railRoute returns the route of a back-edge along the rail at x: out of
+// the source's left side, along the rail, and into the target's left
+// side. An end attached to a named port goes through the port's stub
+// instead. As in directRoute, edges inside one collapsed group's
+// placeholder are not drawn.
+func railRoute(g *FlowGraph, edge graphmodel.Edge[FlowEdgeData], x int) (route []image.Point, ok bool) {
+	fromBounds, fromGroup, ok1 := endpointBounds(g, edge.FromID)
+	toBounds, toGroup, ok2 := endpointBounds(g, edge.ToID)
+	if !ok1 || !ok2 {
+		return nil, false
+	}
+	if fromGroup != graphmodel.NoGroup && fromGroup == toGroup {
+		return nil, false
+	}
+
+	p1 := image.Pt(fromBounds.Min.X, rectCenter(fromBounds).Y)
+	if port, ok := portPos(g, edge.FromID, edge.FromPort, fromGroup); ok {
+		route = append(route, port)
+		p1 = drawutil.PortStub(fromBounds, port)
+	}
+	p2 := image.Pt(toBounds.Min.X, rectCenter(toBounds).Y)
+	var tail []image.Point
+	if port, ok := portPos(g, edge.ToID, edge.ToPort, toGroup); ok {
+		p2 = drawutil.PortStub(toBounds, port)
+		tail = append(tail, port)
+	}
+	route = append(route, drawutil.RailRoute(p1, p2, x)...)
+	return append(route, tail...), true
+}
+
+// portPos returns the world position of a named port on a node, unless
+// the name is empty or the node is hidden in a collapsed group.
+func portPos(g *FlowGraph, nodeID int, port string, group int) (image.Point, bool) {
+	if port == "" || group != graphmodel.NoGroup {
+		return image.Point{}, false
+	}
+	return graphmodel.PortPos(g.Node(nodeID).Data, port)
+}
+
+// shiftParallel offsets the endpoints of an edge that shares its pair of
+// nodes with other edges.
+func shiftParallel(g *FlowGraph, edgeID int, p1, p2 image.Point) (image.Point, image.Point) {
+	index, count := g.ParallelIndex(edgeID)
+	if count > 1 {
+		// Spread offsets symmetrically: -1,+1 for two edges; -2,0,+2 for three
+		off := 2*index - (count - 1)
+		if abs(p2.X-p1.X) >= abs(p2.Y-p1.Y) {
+			// Mostly horizontal: shift rows, staying within the node side
+			dy := clamp(off, -1, 1)
+			p1.Y += dy
+			p2.Y += dy
+		} else {
+			// Mostly vertical: shift columns, two per step for readability
+			p1.X += off * 2
+			p2.X += off * 2
+		}
+	}
+	return p1, p2
+}
+
+// endpointBounds returns the rectangle an edge attaches to for a node:
+// the node's bounds, or the placeholder of its outermost collapsed group
+// (returned as group, otherwise NoGroup).
+func endpointBounds(g *FlowGraph, nodeID int) (r image.Rectangle, group int, ok bool) {
+	node := g.Node(nodeID)
+	if node == nil {
+		return r, graphmodel.NoGroup, false
+	}
+	if group = g.CollapsedAncestor(nodeID); group != graphmodel.NoGroup {
+		return g.GroupBounds(group), group, true
+	}
+	return graphmodel.BoundsOf(node.Data), graphmodel.NoGroup, true
+}
+
+// endpointShape returns the outline an edge attaches to for a node: its
+// type's shape, or a box for a collapsed group's placeholder.
+func endpointShape(g *FlowGraph, nodeID, group int) drawutil.Shape {
+	if group != graphmodel.NoGroup {
+		return drawutil.ShapeBox
+	}
+	return nodeTypeInfo[g.Node(nodeID).Data.Type].Shape
+}
+
+// rectCenter matches graphmodel.CenterOf for a rectangle.
+func rectCenter(r image.Rectangle) image.Point {
+	return r.Min.Add(r.Size().Div(2))
+}
+
+// drawGroupFrame draws a dashed frame with a title in its top border.
+// r is in buffer coordinates; parts outside the buffer are clipped.
+func drawGroupFrame(buf *cellbuf.Buffer, r image.Rectangle, title string, frame, titleStyle cellbuf.StyleKey) {
+	if r.Dx() < 2 || r.Dy() < 2 {
+		return
+	}
+	x0, y0, x1, y1 := r.Min.X, r.Min.Y, r.Max.X-1, r.Max.Y-1
+	for x := x0 + 1; x < x1; x++ {
+		buf.Set(x, y0, '┄', frame)
+		buf.Set(x, y1, '┄', frame)
+	}
+	for y := y0 + 1; y < y1; y++ {
+		buf.Set(x0, y, '┆', frame)
+		buf.Set(x1, y, '┆', frame)
+	}
+	buf.Set(x0, y0, '┌', frame)
+	buf.Set(x1, y0, '┐', frame)
+	buf.Set(x0, y1, '└', frame)
+	buf.Set(x1, y1, '┘', frame)
+
+	if title != "" && r.Dx() > lipgloss.Width(title)+4 {
+		buf.SetString(x0+2, y0, " "+title+" ", titleStyle)
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
 // buildEdgeLabelLayers creates a Layer for each edge that has a label,
-// positioned at the edge midpoint.
-func buildEdgeLabelLayers(g *FlowGraph, camX, camY int, viewport image.Rectangle) []*lipgloss.Layer {
+// placed beside the edge's route by placeEdgeLabels. Labels are left out
+// when zoomed out.
+func buildEdgeLabelLayers(g *FlowGraph, cam camera, viewport image.Rectangle, orthogonal bool) []*lipgloss.Layer {
+	if cam.Scale > 1 {
+		return nil
+	}
 	labelStyle := lipgloss.NewStyle().
-		Foreground(c("#00ffc8")).
+		Foreground(edgeLblColor).
 		Background(c("#080e0b")).
 		Bold(true)
 
 	var layers []*lipgloss.Layer
-
+	placed := placeEdgeLabels(g, orthogonal)
+	offset := viewport.Min.Sub(cam.Pos)
 	for _, edge := range g.Edges() {
-		if edge.Data.Label == "" {
+		r, ok := placed[edge.ID]
+		if !ok {
 			continue
 		}
-		fromNode := g.Node(edge.FromID)
-		toNode := g.Node(edge.ToID)
-		if fromNode == nil || toNode == nil {
-			continue
-		}
-
-		fromBounds := graphmodel.BoundsOf(fromNode.Data)
-		toBounds := graphmodel.BoundsOf(toNode.Data)
-		p1 := drawutil.EdgeExit(fromBounds, graphmodel.CenterOf(toNode.Data))
-		p2 := drawutil.EdgeExit(toBounds, graphmodel.CenterOf(fromNode.Data))
+		pos := r.Min.Add(offset)
+		layer := lipgloss.NewLayer(labelStyle.Render(edge.Data.Label)).
+			X(pos.X).Y(pos.Y).Z(3).
+			ID(fmt.Sprintf("elbl-%d", edge.ID))
+		layers = append(layers, layer)
+	}
 
-		// Midpoint in screen coords
-		mx := (p1.X+p2.X)/2 - camX + viewport.Min.X
-		my := (p1.Y+p2.Y)/2 - camY + viewport.Min.Y
+	return layers
+}
 
-		// Offset: above if mostly horizontal, right if mostly vertical
-		dx := math.Abs(float64(p2.X - p1.X))
-		dy := math.Abs(float64(p2.Y - p1.Y))
-		if dx >= dy {
-			mx -= len(edge.Data.Label) / 2
-			my -= 1
-		} else {
-			mx += 1
+// placeEdgeLabels returns the world rectangle of each edge label, keyed
+// by edge ID. Labels are placed in edge order along their drawn routes,
+// keeping clear of nodes, collapsed groups, edge lines, group frames and
+// each other where there is room (see drawutil.LabelPlacer).
+func placeEdgeLabels(g *FlowGraph, orthogonal bool) map[int]image.Rectangle {
+	var nodes []image.Rectangle
+	for _, n := range g.Nodes() {
+		if !g.Hidden(n.ID) {
+			nodes = append(nodes, graphmodel.BoundsOf(n.Data))
+		}
+	}
+	for _, grp := range g.Groups() {
+		if grp.Collapsed && !g.GroupHidden(grp.ID) {
+			nodes = append(nodes, g.GroupBounds(grp.ID))
+		}
+	}
+	lp := drawutil.NewLabelPlacer(nodes)
+
+	for _, grp := range g.Groups() {
+		if !grp.Collapsed && !g.GroupHidden(grp.ID) {
+			r := g.GroupBounds(grp.ID)
+			lp.AddLine(drawutil.PolylinePoints([]image.Point{
+				r.Min, {r.Max.X - 1, r.Min.Y}, r.Max.Sub(image.Pt(1, 1)), {r.Min.X, r.Max.Y - 1}, r.Min,
+			}))
+		}
+	}
+	paths := make(map[int][]image.Point)
+	router := newEdgeRouter(g)
+	for _, edge := range g.Edges() {
+		if route, ok := router.route(edge, orthogonal); ok {
+			paths[edge.ID] = drawutil.PolylinePoints(route)
+			lp.AddLine(paths[edge.ID])
 		}
-
-		rendered := labelStyle.Render(edge.Data.Label)
-		layer := lipgloss.NewLayer(rendered).
-			X(mx).Y(my).Z(3).
-			ID(fmt.Sprintf("elbl-%d-%d", edge.FromID, edge.ToID))
-		layers = append(layers, layer)
 	}
 
-	return layers
+	placed := make(map[int]image.Rectangle)
+	for _, edge := range g.Edges() {
+		if path, ok := paths[edge.ID]; ok && edge.Data.Label != "" {
+			placed[edge.ID] = lp.Place(path, lipgloss.Width(edge.Data.Label))
+		}
+	}
+	return placed
 }
 
-// buildNodeLayers creates a Layer for each visible node.
+// buildNodeLayers creates a Layer for each visible node, plus a
+// placeholder box for each collapsed group. In a diff view, diff holds
+// the change for each differing node and overrides its colors. With
+// shapes set, nodes are drawn as their flowchart symbols instead of
+// bordered boxes. Zoomed out, nodes are drawn with less detail (see
+// buildZoomedNodeLayers).
 // screenX = node.X - camX, screenY = node.Y - camY + offsetY.
-func buildNodeLayers(g *FlowGraph, camX, camY int, viewport image.Rectangle,
-	selectedID, execID *int) []*lipgloss.Layer {
+func buildNodeLayers(g *FlowGraph, cam camera, viewport image.Rectangle,
+	selectedID, execID, selectedGroupID *int, diff map[int]graphmodel.Change, shapes bool) []*lipgloss.Layer {
 
-	var layers []*lipgloss.Layer
+	if cam.Scale > 1 {
+		return buildZoomedNodeLayers(g, cam, viewport, selectedID, execID, selectedGroupID, diff)
+	}
+	camX, camY := cam.Pos.X, cam.Pos.Y
+	layers := buildGroupPlaceholderLayers(g, camX, camY, viewport, selectedGroupID, execID)
 
 	for _, node := range g.Nodes() {
+		if g.Hidden(node.ID) {
+			continue
+		}
 		d := node.Data
 		info := nodeTypeInfo[d.Type]
 
@@ -169,6 +524,15 @@ func buildNodeLayers(g *FlowGraph, camX, camY int, viewport image.Rectangle,
 		if execID != nil && node.ID == *execID {
 			bc, tc, bg = execBorder, execText, execBG
 		}
+		tagText := info.Tag
+		if ch, ok := diff[node.ID]; ok {
+			bc, tc, tagText = diffColor(ch), diffColor(ch), diffMark(ch)+tagText
+		}
+
+		if shapes {
+			layers = append(layers, shapeNodeLayers(node.ID, info, d.Text, tagText, sx, sy, bc, tc, bg)...)
+			continue
+		}
 
 		// Build the styled box
 		border := borderForType(d.Type)
@@ -185,9 +549,7 @@ func buildNodeLayers(g *FlowGraph, camX, camY int, viewport image.Rectangle,
 		if maxLen < 0 {
 			maxLen = 0
 		}
-		if len(label) > maxLen {
-			label = label[:maxLen]
-		}
+		label = ansi.Truncate(label, maxLen, "")
 
 		textStyle := lipgloss.NewStyle().
 			Foreground(tc).
@@ -200,11 +562,11 @@ func buildNodeLayers(g *FlowGraph, camX, camY int, viewport image.Rectangle,
 		rendered := boxStyle.Render(content)
 
 		// Tag overlay (e.g. [P], [?], [IO]) — rendered separately above the box
-		if info.Tag != "" {
+		if tagText != "" {
 			tag := lipgloss.NewStyle().
 				Foreground(bc).
 				Background(bg).
-				Render(fmt.Sprintf("[%s]", info.Tag))
+				Render(fmt.Sprintf("[%s]", tagText))
 			tagLayer := lipgloss.NewLayer(tag).
 				X(sx + 2).Y(sy).Z(3).
 				ID(fmt.Sprintf("tag-%d", node.ID))
@@ -220,6 +582,114 @@ func buildNodeLayers(g *FlowGraph, camX, camY int, viewport image.Rectangle,
 	return layers
 }
 
+// Style keys of a node drawn as a shape. Each node renders them with its
+// own colors.
+const (
+	styleShapeOutside cellbuf.StyleKey = iota
+	styleShapeBorder
+	styleShapeFill
+	styleShapeText
+)
+
+// shapeNodeLayers draws a node as its flowchart symbol at screen (sx, sy),
+// with its type tag in the top edge. Cells of the node's rectangle outside
+// the shape keep the canvas background.
+func shapeNodeLayers(id int, info NodeTypeInfo, text, tag string, sx, sy int, bc, tc, bg color.Color) []*lipgloss.Layer {
+	r := image.Rect(0, 0, info.W, info.H)
+	buf := cellbuf.New(info.W, info.H, styleShapeOutside)
+	drawutil.DrawShape(buf, r, info.Shape, text, styleShapeBorder, styleShapeFill, styleShapeText)
+	rendered := buf.Render(map[cellbuf.StyleKey]lipgloss.Style{
+		styleShapeOutside: lipgloss.NewStyle().Background(colorBG),
+		styleShapeBorder:  lipgloss.NewStyle().Foreground(bc).Background(bg),
+		styleShapeFill:    lipgloss.NewStyle().Background(bg),
+		styleShapeText:    lipgloss.NewStyle().Foreground(tc).Background(bg).Bold(true),
+	})
+	layers := []*lipgloss.Layer{
+		lipgloss.NewLayer(rendered).X(sx).Y(sy).Z(2).ID(fmt.Sprintf("node-%d", id)),
+	}
+	if tag != "" {
+		x0, _, _ := drawutil.ShapeSpan(r, info.Shape, 0)
+		rendered := lipgloss.NewStyle().Foreground(bc).Background(bg).Render(fmt.Sprintf("[%s]", tag))
+		layers = append(layers, lipgloss.NewLayer(rendered).
+			X(sx+x0+2).Y(sy).Z(3).
+			ID(fmt.Sprintf("tag-%d", id)))
+	}
+	return layers
+}
+
+// diffColor returns the node color for a diff change.
+func diffColor(ch graphmodel.Change) color.Color {
+	switch {
+	case ch.Has(graphmodel.Added):
+		return diffAdded
+	case ch.Has(graphmodel.Removed):
+		return diffRemoved
+	default:
+		return diffChanged
+	}
+}
+
+// diffMark returns the tag prefix for a diff change.
+func diffMark(ch graphmodel.Change) string {
+	switch {
+	case ch.Has(graphmodel.Added):
+		return "+"
+	case ch.Has(graphmodel.Removed):
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// buildGroupPlaceholderLayers renders each visible collapsed group as a
+// single box, highlighted when selected or when it hides the executing
+// node.
+func buildGroupPlaceholderLayers(g *FlowGraph, camX, camY int, viewport image.Rectangle,
+	selectedGroupID, execID *int) []*lipgloss.Layer {
+
+	var layers []*lipgloss.Layer
+	for _, grp := range g.Groups() {
+		if !grp.Collapsed || g.GroupHidden(grp.ID) {
+			continue
+		}
+		r := g.GroupBounds(grp.ID)
+		if r.Empty() {
+			continue
+		}
+		sx := r.Min.X - camX + viewport.Min.X
+		sy := r.Min.Y - camY + viewport.Min.Y
+		if !image.Rect(sx, sy, sx+r.Dx(), sy+r.Dy()).Overlaps(viewport) {
+			continue
+		}
+
+		bc, tc, bg := groupBorder, groupTitle, colorBG
+		if selectedGroupID != nil && grp.ID == *selectedGroupID {
+			bc, tc, bg = selBorder, selText, selBG
+		}
+		if execID != nil && g.CollapsedAncestor(*execID) == grp.ID {
+			bc, tc, bg = execBorder, execText, execBG
+		}
+
+		label := "▸ " + grp.Label
+		label = ansi.Truncate(label, max(r.Dx()-4, 0), "")
+		content := lipgloss.NewStyle().Foreground(tc).Background(bg).Bold(true).Render(label)
+		rendered := lipgloss.NewStyle().
+			Border(lipgloss.NormalBorder()).
+			BorderForeground(bc).
+			Background(bg).
+			Width(r.Dx() - 2).
+			AlignHorizontal(lipgloss.Center).
+			Render(content)
+
+		tag := lipgloss.NewStyle().Foreground(bc).Background(bg).Render("[+]")
+		layers = append(layers,
+			lipgloss.NewLayer(rendered).X(sx).Y(sy).Z(2).ID(fmt.Sprintf("group-%d", grp.ID)),
+			lipgloss.NewLayer(tag).X(sx+2).Y(sy).Z(3).ID(fmt.Sprintf("gtag-%d", grp.ID)),
+		)
+	}
+	return layers
+}
+
 // nodeCenter returns the screen-space center of a node.
 func nodeCenter(d FlowNodeData, camX, camY int, viewport image.Rectangle) image.Point {
 	c := graphmodel.CenterOf(d)
@@ -0,0 +1,185 @@
+package grailui
+
+import (
+	"image"
+
+	"charm.land/lipgloss/v2"
+	"github.com/wesen/grail/pkg/cellbuf"
+	"github.com/wesen/grail/pkg/drawutil"
+	"github.com/wesen/grail/pkg/graphmodel"
+)
+
+// Size of the minimap, border included. It sits in the bottom-right
+// corner of the canvas, clear of the panel's separator, and is left out
+// when the canvas is too small.
+const minimapW, minimapH = 26, 9
+
+// Minimap style keys. Each key plus mmInView is the same style inside
+// the camera's viewport, shown with a lighter background.
+const (
+	mmEmpty  cellbuf.StyleKey = 0
+	mmNode   cellbuf.StyleKey = 2
+	mmExec   cellbuf.StyleKey = 4
+	mmBorder cellbuf.StyleKey = 6
+	mmInView cellbuf.StyleKey = 1
+)
+
+var (
+	mmViewBG = c("#123026")
+
+	minimapStyles = map[cellbuf.StyleKey]lipgloss.Style{
+		mmEmpty:             lipgloss.NewStyle().Background(colorBG),
+		mmEmpty + mmInView:  lipgloss.NewStyle().Background(mmViewBG),
+		mmNode:              lipgloss.NewStyle().Foreground(groupTitle).Background(colorBG),
+		mmNode + mmInView:   lipgloss.NewStyle().Foreground(edgeLblColor).Background(mmViewBG),
+		mmExec:              lipgloss.NewStyle().Foreground(execBorder).Background(colorBG),
+		mmExec + mmInView:   lipgloss.NewStyle().Foreground(execBorder).Background(mmViewBG),
+		mmBorder:            lipgloss.NewStyle().Foreground(groupBorder).Background(colorBG),
+		mmBorder + mmInView: lipgloss.NewStyle().Foreground(groupTitle).Background(colorBG).Bold(true),
+	}
+)
+
+// minimapLayout returns the screen rectangle of the minimap and the
+// camera that maps the world onto the cells inside its border. The map
+// shows the whole chart together with the part of the world the canvas
+// shows, scaled down evenly to fit and centred. ok is false when the
+// canvas has no room for a minimap.
+func (m Model) minimapLayout() (r image.Rectangle, cam camera, ok bool) {
+	canvas := m.canvasRect()
+	if canvas.Dx() < minimapW+3 || canvas.Dy() < minimapH+1 {
+		return r, cam, false
+	}
+	r = image.Rect(canvas.Max.X-minimapW-2, canvas.Max.Y-minimapH, canvas.Max.X-2, canvas.Max.Y)
+	iw, ih := minimapW-2, minimapH-2
+
+	world := chartBounds(m.Graph).Union(m.viewportWorld())
+	scale := max((world.Dx()+iw-1)/iw, (world.Dy()+ih-1)/ih, 1)
+	pad := image.Pt(iw*scale-world.Dx(), ih*scale-world.Dy()).Div(2)
+	return r, camera{Pos: world.Min.Sub(pad), Scale: scale}, true
+}
+
+// viewportWorld returns the part of the world the canvas shows.
+func (m Model) viewportWorld() image.Rectangle {
+	cam := m.camera()
+	canvas := m.canvasRect()
+	return image.Rectangle{cam.Pos, cam.Pos.Add(canvas.Size().Mul(cam.Scale))}
+}
+
+// centerOn moves the camera so that world point p is in the middle of
+// the canvas, stopping any pan under way.
+func (m *Model) centerOn(p image.Point) {
+	cam := m.camFor(p)
+	m.CamX, m.CamY = cam.X, cam.Y
+	m.CamTarget = nil
+}
+
+// minimapClick jumps the camera to the world point under a click at
+// screen point p on the minimap. It reports whether p was on the map.
+func (m *Model) minimapClick(p image.Point) bool {
+	if !m.Minimap {
+		return false
+	}
+	r, cam, ok := m.minimapLayout()
+	if !ok || !p.In(r) {
+		return false
+	}
+	inner := r.Inset(1)
+	cell := image.Pt(clamp(p.X, inner.Min.X, inner.Max.X-1), clamp(p.Y, inner.Min.Y, inner.Max.Y-1))
+	m.centerOn(cam.toWorld(cell.Sub(inner.Min)))
+	return true
+}
+
+// buildMinimapLayer draws every visible node and collapsed group of the
+// chart as blocks of dots in a small bordered map, with the executing
+// node picked out and the canvas's viewport shaded. Nothing is returned
+// when the canvas has no room for it.
+func buildMinimapLayer(m Model) *lipgloss.Layer {
+	r, cam, ok := m.minimapLayout()
+	if !ok {
+		return nil
+	}
+	g := m.Graph
+	buf := cellbuf.New(r.Dx(), r.Dy(), mmEmpty)
+	inner := image.Rect(0, 0, r.Dx(), r.Dy()).Inset(1)
+
+	// Blocks are Braille dots, 2×4 to a cell, so that nodes next to each
+	// other stay apart
+	dotMin := inner.Min.Mul(2)
+	dotMin.Y *= 2
+	mark := func(world image.Rectangle, style cellbuf.StyleKey) {
+		d := world.Sub(cam.Pos)
+		dots := image.Rect(drawutil.FloorDiv(2*d.Min.X, cam.Scale), drawutil.FloorDiv(4*d.Min.Y, cam.Scale),
+			-drawutil.FloorDiv(-2*d.Max.X, cam.Scale), -drawutil.FloorDiv(-4*d.Max.Y, cam.Scale))
+		dots = dots.Add(dotMin).Intersect(image.Rectangle{dotMin, dotMin.Add(image.Pt(2*inner.Dx(), 4*inner.Dy()))})
+		for y := dots.Min.Y; y < dots.Max.Y; y++ {
+			for x := dots.Min.X; x < dots.Max.X; x++ {
+				drawutil.PlotBraille(buf, x, y, style)
+			}
+		}
+	}
+	execGroup := graphmodel.NoGroup
+	if m.ExecID != nil {
+		execGroup = g.CollapsedAncestor(*m.ExecID)
+	}
+	for _, grp := range g.Groups() {
+		if grp.Collapsed && !g.GroupHidden(grp.ID) {
+			style := mmNode
+			if grp.ID == execGroup {
+				style = mmExec
+			}
+			mark(g.GroupBounds(grp.ID), style)
+		}
+	}
+	for _, n := range g.Nodes() {
+		if g.Hidden(n.ID) {
+			continue
+		}
+		style := mmNode
+		if m.ExecID != nil && n.ID == *m.ExecID {
+			style = mmExec
+		}
+		mark(graphmodel.BoundsOf(n.Data), style)
+	}
+
+	// Shade the viewport, and light up the border beside it
+	view := cam.rect(m.viewportWorld()).Add(inner.Min)
+	for y := range buf.H {
+		for x := range buf.W {
+			p := image.Pt(x, y)
+			if p.In(inner) && p.In(view) {
+				cell := buf.Cells[y][x]
+				buf.Set(x, y, cell.Ch, cell.Style+mmInView)
+			}
+		}
+	}
+	drawMinimapBorder(buf, view)
+
+	return lipgloss.NewLayer(buf.Render(minimapStyles)).X(r.Min.X).Y(r.Min.Y).Z(5).ID("minimap")
+}
+
+// drawMinimapBorder draws the frame of the minimap with its title. The
+// parts of the frame in line with the viewport view are highlighted, as
+// rulers for where the viewport is.
+func drawMinimapBorder(buf *cellbuf.Buffer, view image.Rectangle) {
+	x1, y1 := buf.W-1, buf.H-1
+	styleAt := func(x, y int) cellbuf.StyleKey {
+		if (x > 0 && x < x1 && x >= view.Min.X && x < view.Max.X) ||
+			(y > 0 && y < y1 && y >= view.Min.Y && y < view.Max.Y) {
+			return mmBorder + mmInView
+		}
+		return mmBorder
+	}
+	for x := 1; x < x1; x++ {
+		buf.Set(x, 0, '─', styleAt(x, 0))
+		buf.Set(x, y1, '─', styleAt(x, y1))
+	}
+	for y := 1; y < y1; y++ {
+		buf.Set(0, y, '│', styleAt(0, y))
+		buf.Set(x1, y, '│', styleAt(x1, y))
+	}
+	buf.Set(0, 0, '┌', mmBorder)
+	buf.Set(x1, 0, '┐', mmBorder)
+	buf.Set(0, y1, '└', mmBorder)
+	buf.Set(x1, y1, '┘', mmBorder)
+	buf.SetString(2, 0, " MAP ", mmBorder)
+}
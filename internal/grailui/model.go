@@ -1,11 +1,17 @@
 package grailui
 
 import (
+	"errors"
+	"image"
+	"io/fs"
 	"time"
 
 	tea "charm.land/bubbletea/v2"
 	"charm.land/bubbles/v2/textinput"
 	"github.com/wesen/grail/internal/flowinterp"
+	"github.com/wesen/grail/pkg/cellbuf"
+	"github.com/wesen/grail/pkg/drawutil"
+	"github.com/wesen/grail/pkg/graphmodel"
 )
 
 // Tool is the current interaction mode.
@@ -17,25 +23,58 @@ const (
 	ToolConnect
 )
 
+// EdgeMode selects how edge lines are drawn.
+type EdgeMode int
+
+const (
+	EdgeChars   EdgeMode = iota // line characters: ─ │ / \
+	EdgeBraille                 // Braille dots, 2×4 per cell
+)
+
 // Model is the main application state.
 type Model struct {
-	Width, Height  int
-	MouseX, MouseY int
-	CamX, CamY    int
-	Graph          *FlowGraph
-	SelectedID     *int
-	ExecID         *int
-	CurrentTool    Tool
-	AddNodeType    string // node type for add tool
+	Width, Height   int
+	MouseX, MouseY  int
+	CamX, CamY      int
+	Graph           *FlowGraph
+	SelectedID      *int
+	SelectedGroupID *int
+	SelectedEdgeID  *int
+	ExecID          *int
+	CurrentTool     Tool
+	AddNodeType     string // node type for add tool
+	EdgeMode        EdgeMode
+	Orthogonal      bool // route edges with horizontal and vertical legs
+	Hops            bool // show edge crossings as hops instead of ┼
+	Shapes          bool // draw nodes as flowchart symbols instead of boxes
+	Zoom            int  // zoom level, ZoomFull and out (see zoomScales)
+	Minimap         bool // show the minimap in the corner of the canvas
+	Follow          bool // keep the executing node on the canvas
+
+	// CamTarget is where the camera is panning to, a step per CamTickMsg,
+	// or nil.
+	CamTarget *image.Point
 
 	// Drag state
-	Dragging   bool
-	DragNodeID int
-	DragOffX   int
-	DragOffY   int
+	Dragging    bool
+	DragNodeID  int
+	DragGroupID int
+	DragOffX    int
+	DragOffY    int
+
+	// Pan-drag state: the screen point and camera where a middle-button
+	// or space+left drag of the canvas started
+	Panning   bool
+	PanFromX  int
+	PanFromY  int
+	PanCamX   int
+	PanCamY   int
+	SpaceHeld bool // space was pressed, so the next left drag pans
 
 	// Connect state
-	ConnectFromID *int
+	ConnectFromID   *int
+	ConnectFromPort string // port nearest to the click on the source node
+	PickTargetID    *int   // candidate target while connecting from the keyboard
 
 	// Interpreter state
 	Interp      *flowinterp.Interpreter
@@ -48,9 +87,30 @@ type Model struct {
 	// Edit modal state
 	EditOpen    bool
 	EditNodeID  int
+	EditGroupID *int // set when editing a group's label instead of a node
 	EditLabel   textinput.Model
 	EditCode    textinput.Model
 	EditFocus   int // 0=label, 1=code
+
+	// Command palette state
+	PaletteOpen  bool
+	PaletteQuery string
+	PaletteSel   int // index of the chosen result
+
+	// Keymap binds keys to actions (see NewKeymap)
+	Keymap Keymap
+
+	// Document state
+	Path   string // chart file, saved with ctrl+s; empty for the demo chart
+	Status string // last save/load message, shown in the footer
+
+	// DiffStatus marks the nodes that differ in a diff view (see
+	// NewDiffModel). The model is read-only while it is set.
+	DiffStatus map[int]graphmodel.Change
+
+	// edgeBuf is the grid/edge canvas, kept between frames so unchanged
+	// rows are not re-rendered. Copies of the model share it.
+	edgeBuf *cellbuf.Buffer
 }
 
 // NewModel creates the initial model with the demo flowchart.
@@ -59,8 +119,38 @@ func NewModel() Model {
 		Graph:       MakeInitialGraph(),
 		AddNodeType: "process",
 		DragNodeID:  -1,
+		DragGroupID: -1,
 		AutoSpeed:   400 * time.Millisecond,
+		Keymap:      defaultKeymap,
+		edgeBuf:     cellbuf.New(0, 0, styleBG),
+	}
+}
+
+// NewFileModel creates a model editing the chart at path. A missing file
+// starts an empty chart that is created on the first save.
+func NewFileModel(path string) (Model, error) {
+	m := NewModel()
+	m.Path = path
+	g, err := LoadGraph(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		m.Graph = NewFlowGraph()
+		m.Status = "new file " + path
+	case err != nil:
+		return m, err
+	default:
+		m.Graph = g
+	}
+	return m, nil
+}
+
+// edgeStyle returns how the canvas draws edges.
+func (m Model) edgeStyle() edgeStyle {
+	s := edgeStyle{Mode: m.EdgeMode, Orthogonal: m.Orthogonal}
+	if m.Hops {
+		s.Crossing = drawutil.CrossHop
 	}
+	return s
 }
 
 // Init implements tea.Model.
@@ -5,6 +5,7 @@ import (
 	"image"
 
 	tea "charm.land/bubbletea/v2"
+	"github.com/wesen/grail/pkg/graphmodel"
 )
 
 // handleMouse processes mouse events and returns updated model + command.
@@ -13,51 +14,142 @@ func handleMouse(m Model, msg tea.MouseMsg, canvasRect image.Rectangle) (Model,
 	m.MouseX = mouse.X
 	m.MouseY = mouse.Y
 
-	// Only process mouse events inside the canvas region
-	if !image.Pt(mouse.X, mouse.Y).In(canvasRect) {
+	// A pan-drag goes on when the mouse leaves the canvas
+	if m.Panning {
+		return handlePanDrag(m, msg), nil
+	}
+
+	// The middle button, or the left one with space held, drags the
+	// canvas. Any other click or wheel turn lets go of space, which most
+	// terminals never report released. Any click ends picking a target
+	// from the keyboard.
+	inCanvas := image.Pt(mouse.X, mouse.Y).In(canvasRect)
+	_, click := msg.(tea.MouseClickMsg)
+	_, wheel := msg.(tea.MouseWheelMsg)
+	if click && m.PickTargetID != nil {
+		m.endPick()
+	}
+	if click && inCanvas && (mouse.Button == tea.MouseMiddle || (mouse.Button == tea.MouseLeft && m.SpaceHeld)) {
+		m.Panning = true
+		m.PanFromX, m.PanFromY = mouse.X, mouse.Y
+		m.PanCamX, m.PanCamY = m.CamX, m.CamY
+		m.CamTarget = nil
 		return m, nil
 	}
+	if click || wheel {
+		m.SpaceHeld = false
+	}
 
-	// World coordinates from screen position
-	worldX := mouse.X - canvasRect.Min.X + m.CamX
-	worldY := mouse.Y - canvasRect.Min.Y + m.CamY
+	// Only process mouse events inside the canvas region; left clicks on
+	// the minimap move the camera
+	if !inCanvas {
+		return m, nil
+	}
+	if click && mouse.Button == tea.MouseLeft && m.minimapClick(image.Pt(mouse.X, mouse.Y)) {
+		return m, nil
+	}
+
+	// World coordinates from screen position; clicks pick what is drawn
+	// in the cell when zoomed out (see Model.pick)
+	cell := image.Pt(mouse.X, mouse.Y).Sub(canvasRect.Min)
+	world := m.camera().toWorld(cell)
+	worldX, worldY := world.X, world.Y
 
 	switch msg.(type) {
+	case tea.MouseWheelMsg:
+		m = handleWheel(m, mouse, cell)
+
 	case tea.MouseMotionMsg:
 		if m.Dragging && m.DragNodeID >= 0 {
 			newX := worldX - m.DragOffX
 			newY := worldY - m.DragOffY
 			m.Graph.MoveNode(m.DragNodeID, image.Pt(newX, newY), SetPos)
 		}
+		if m.Dragging && m.DragGroupID >= 0 {
+			newMin := image.Pt(worldX-m.DragOffX, worldY-m.DragOffY)
+			cur := m.Graph.GroupBounds(m.DragGroupID).Min
+			m.Graph.MoveGroup(m.DragGroupID, newMin.Sub(cur), SetPos)
+		}
 
 	case tea.MouseClickMsg:
 		if mouse.Button == tea.MouseLeft {
-			m = handleLeftClick(m, worldX, worldY)
+			p := m.pick(cell)
+			m = handleLeftClick(m, p.X, p.Y)
 		}
 
 	case tea.MouseReleaseMsg:
 		if m.Dragging {
 			m.Dragging = false
 			m.DragNodeID = -1
+			m.DragGroupID = -1
 		}
 	}
 
 	return m, nil
 }
 
+// handleWheel zooms around canvas cell at with ctrl held, and otherwise
+// scrolls the canvas: vertically, or horizontally with shift held or a
+// sideways wheel.
+func handleWheel(m Model, mouse tea.Mouse, at image.Point) Model {
+	if mouse.Mod.Contains(tea.ModCtrl) {
+		switch mouse.Button {
+		case tea.MouseWheelUp:
+			m.zoomTo(m.Zoom-1, at)
+		case tea.MouseWheelDown:
+			m.zoomTo(m.Zoom+1, at)
+		}
+		return m
+	}
+	shift := mouse.Mod.Contains(tea.ModShift)
+	switch {
+	case mouse.Button == tea.MouseWheelLeft || (mouse.Button == tea.MouseWheelUp && shift):
+		m.pan("left")
+	case mouse.Button == tea.MouseWheelRight || (mouse.Button == tea.MouseWheelDown && shift):
+		m.pan("right")
+	case mouse.Button == tea.MouseWheelUp:
+		m.pan("up")
+	case mouse.Button == tea.MouseWheelDown:
+		m.pan("down")
+	}
+	return m
+}
+
+// handlePanDrag moves the camera with a pan-drag so that the world point
+// under the mouse when the drag started stays under it, and ends the drag
+// when the button is released. Space is let go of then too, for
+// terminals that do not report key releases.
+func handlePanDrag(m Model, msg tea.MouseMsg) Model {
+	mouse := msg.Mouse()
+	switch msg.(type) {
+	case tea.MouseMotionMsg:
+		d := image.Pt(mouse.X-m.PanFromX, mouse.Y-m.PanFromY).Mul(zoomScales[m.Zoom])
+		m.CamX, m.CamY = m.PanCamX-d.X, m.PanCamY-d.Y
+	case tea.MouseReleaseMsg:
+		m.Panning = false
+		m.SpaceHeld = false
+	}
+	return m
+}
+
 // handleLeftClick dispatches based on current tool, using graphmodel.HitTest.
 func handleLeftClick(m Model, worldX, worldY int) Model {
 	// Hit test using graphmodel (world coordinates)
-	hitNode := m.Graph.HitTest(image.Pt(worldX, worldY))
+	hit := m.Graph.HitTestDeep(image.Pt(worldX, worldY))
 	hitNodeID := -1
-	if hitNode != nil {
-		hitNodeID = hitNode.ID
+	if hit.Node != nil {
+		hitNodeID = hit.Node.ID
 	}
 
 	switch m.CurrentTool {
 	case ToolSelect:
-		if hitNodeID >= 0 {
+		m.SelectedID = nil
+		m.SelectedGroupID = nil
+		m.SelectedEdgeID = nil
+		switch hit.Kind {
+		case graphmodel.HitNode:
 			m.SelectedID = &hitNodeID
+			m.Graph.BringToFront(hitNodeID)
 			// Start drag
 			node := m.Graph.Node(hitNodeID)
 			if node != nil {
@@ -66,8 +158,21 @@ func handleLeftClick(m Model, worldX, worldY int) Model {
 				m.DragOffX = worldX - node.Data.X
 				m.DragOffY = worldY - node.Data.Y
 			}
-		} else {
-			m.SelectedID = nil
+		case graphmodel.HitGroupFrame:
+			// Select the group and drag it by its frame
+			groupID := hit.Group.ID
+			m.SelectedGroupID = &groupID
+			origin := m.Graph.GroupBounds(groupID).Min
+			m.Dragging = true
+			m.DragGroupID = groupID
+			m.DragOffX = worldX - origin.X
+			m.DragOffY = worldY - origin.Y
+		default:
+			// Edges lie under nodes, so they are only hit in the open
+			cam := m.camera()
+			if id, ok := edgeAt(m.Graph, cam, cam.toCanvas(image.Pt(worldX, worldY)), m.Orthogonal); ok {
+				m.SelectedEdgeID = &id
+			}
 		}
 
 	case ToolAdd:
@@ -81,18 +186,30 @@ func handleLeftClick(m Model, worldX, worldY int) Model {
 			Y:    ny,
 			Text: newText,
 		})
+		// Nodes added inside an expanded group join it
+		if hit.Group != nil && !hit.Group.Collapsed {
+			m.Graph.SetParent(id, hit.Group.ID)
+		}
 		m.SelectedID = &id
+		m.SelectedGroupID = nil
+		m.SelectedEdgeID = nil
 		m.CurrentTool = ToolSelect
 
 	case ToolConnect:
+		// Both ends snap to the port nearest to the click
+		pt := image.Pt(worldX, worldY)
 		if m.ConnectFromID == nil {
 			if hitNodeID >= 0 {
 				m.ConnectFromID = &hitNodeID
+				m.ConnectFromPort = ""
+				if p, ok := graphmodel.NearestPort(hit.Node.Data, pt); ok {
+					m.ConnectFromPort = p.Name
+				}
 			}
 		} else {
-			if hitNodeID >= 0 && hitNodeID != *m.ConnectFromID {
-				label := autoEdgeLabel(m.Graph, *m.ConnectFromID)
-				m.Graph.AddEdge(*m.ConnectFromID, hitNodeID, FlowEdgeData{Label: label})
+			// Clicking the source again makes a self-loop
+			if hitNodeID >= 0 {
+				addConnection(m.Graph, *m.ConnectFromID, m.ConnectFromPort, hitNodeID, pt)
 			}
 			m.ConnectFromID = nil
 			m.CurrentTool = ToolSelect
@@ -102,6 +219,51 @@ func handleLeftClick(m Model, worldX, worldY int) Model {
 	return m
 }
 
+// addConnection adds an edge from a node to another, labelled and
+// attached as the connect tool does: see connectLabel for the source end;
+// the target end snaps to the target's port nearest to world point at.
+func addConnection(g *FlowGraph, fromID int, fromPort string, toID int, at image.Point) {
+	to := g.Node(toID)
+	if to == nil {
+		return
+	}
+	label, fromPort := connectLabel(g, fromID, fromPort)
+	toPort := ""
+	if p, ok := graphmodel.NearestPort(to.Data, at); ok {
+		toPort = p.Name
+	}
+	// Parallel edges are only useful as distinct decision branches
+	if label != "" || !g.HasEdge(fromID, toID) {
+		g.AddPortEdge(fromID, fromPort, toID, toPort, FlowEdgeData{Label: label})
+	}
+}
+
+// connectLabel picks the label and source port for a new edge. On a
+// decision, starting near the Y or N port picks that branch unless it is
+// already taken; otherwise the next free branch (and its port) is used.
+func connectLabel(g *FlowGraph, fromID int, fromPort string) (label, port string) {
+	node := g.Node(fromID)
+	if node == nil || node.Data.Type != "decision" {
+		return "", fromPort
+	}
+	if fromPort == "Y" || fromPort == "N" {
+		taken := false
+		for _, e := range g.OutEdges(fromID) {
+			if e.Data.Label == fromPort {
+				taken = true
+			}
+		}
+		if !taken {
+			return fromPort, fromPort
+		}
+	}
+	label = autoEdgeLabel(g, fromID)
+	if label == "" {
+		return "", fromPort
+	}
+	return label, label
+}
+
 // autoEdgeLabel assigns "Y"/"N" labels for decision node edges.
 func autoEdgeLabel(g *FlowGraph, fromID int) string {
 	node := g.Node(fromID)
@@ -0,0 +1,306 @@
+package grailui
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"slices"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/wesen/grail/pkg/drawutil"
+	"github.com/wesen/grail/pkg/graphmodel"
+)
+
+// navDirs maps the navigation actions to directions on the canvas.
+var navDirs = map[string]image.Point{
+	"nav-left":  {-1, 0},
+	"nav-down":  {0, 1},
+	"nav-up":    {0, -1},
+	"nav-right": {1, 0},
+}
+
+// nudgeDirs maps the move actions to a one-cell move of the selection.
+var nudgeDirs = map[string]image.Point{
+	"move-up":    {0, -1},
+	"move-down":  {0, 1},
+	"move-left":  {-1, 0},
+	"move-right": {1, 0},
+}
+
+// Gaps left between a selected node and a node added next to it with A:
+// below it, or to its right when below is taken.
+const addGapY, addGapX = 2, 4
+
+// nodeInDir returns the visible node, other than those in exclude, whose
+// centre is nearest to from in direction dir. Nodes within 45° of dir
+// come before those further off to the side; among them distance along
+// dir counts once and distance across it twice, so nodes in line are
+// preferred. Rows count double, since cells are about twice as tall as
+// they are wide. ok is false when no node lies in that direction.
+func nodeInDir(g *FlowGraph, from, dir image.Point, exclude ...int) (id int, ok bool) {
+	bestScore, bestInCone := 0, false
+	for _, n := range g.Nodes() {
+		if slices.Contains(exclude, n.ID) || g.Hidden(n.ID) {
+			continue
+		}
+		d := graphmodel.CenterOf(n.Data).Sub(from)
+		d.Y *= 2
+		along := d.X*dir.X + d.Y*dir.Y
+		across := abs(d.X*dir.Y - d.Y*dir.X)
+		if along <= 0 {
+			continue
+		}
+		score, inCone := along+2*across, along >= across
+		if !ok || inCone && !bestInCone || inCone == bestInCone && score < bestScore {
+			id, bestScore, bestInCone, ok = n.ID, score, inCone, true
+		}
+	}
+	return id, ok
+}
+
+// tabOrder returns the IDs of the visible nodes in reading order of
+// their centres: top to bottom, then left to right.
+func tabOrder(g *FlowGraph) []int {
+	var ids []int
+	for _, n := range g.Nodes() {
+		if !g.Hidden(n.ID) {
+			ids = append(ids, n.ID)
+		}
+	}
+	slices.SortStableFunc(ids, func(a, b int) int {
+		ca, cb := graphmodel.CenterOf(g.Node(a).Data), graphmodel.CenterOf(g.Node(b).Data)
+		return cmp.Or(cmp.Compare(ca.Y, cb.Y), cmp.Compare(ca.X, cb.X))
+	})
+	return ids
+}
+
+// cycle returns the ID after cur in ids, or before it when back is set,
+// wrapping around. An ID not in ids starts from the first or last.
+func cycle(ids []int, cur int, back bool) int {
+	i := slices.Index(ids, cur)
+	switch {
+	case i < 0 && back:
+		i = len(ids) - 1
+	case i < 0:
+		i = 0
+	case back:
+		i = (i + len(ids) - 1) % len(ids)
+	default:
+		i = (i + 1) % len(ids)
+	}
+	return ids[i]
+}
+
+// selectionCenter returns the world centre of the selected node, group
+// or edge; an edge's is the middle cell of its line.
+func (m Model) selectionCenter() (image.Point, bool) {
+	g := m.Graph
+	switch {
+	case m.SelectedID != nil && g.Node(*m.SelectedID) != nil:
+		return graphmodel.CenterOf(g.Node(*m.SelectedID).Data), true
+	case m.SelectedGroupID != nil && g.Group(*m.SelectedGroupID) != nil:
+		return rectCenter(g.GroupBounds(*m.SelectedGroupID)), true
+	case m.SelectedEdgeID != nil && g.Edge(*m.SelectedEdgeID) != nil:
+		if route, ok := edgeRoute(g, *g.Edge(*m.SelectedEdgeID), m.Orthogonal); ok {
+			path := drawutil.PolylinePoints(route)
+			return path[len(path)/2], true
+		}
+	}
+	return image.Point{}, false
+}
+
+// selectNode selects a node alone and pans to it if it is not well in
+// view.
+func (m *Model) selectNode(id int) {
+	m.SelectedID = &id
+	m.SelectedGroupID = nil
+	m.SelectedEdgeID = nil
+	m.reveal(graphmodel.BoundsOf(m.Graph.Node(id).Data))
+}
+
+// navigate selects the nearest node in direction dir from the selection,
+// or from the middle of the canvas when nothing is selected.
+func (m *Model) navigate(dir image.Point) {
+	from, ok := m.selectionCenter()
+	if !ok {
+		from = rectCenter(m.viewportWorld())
+	}
+	var exclude []int
+	if m.SelectedID != nil {
+		exclude = append(exclude, *m.SelectedID)
+	}
+	if id, ok := nodeInDir(m.Graph, from, dir, exclude...); ok {
+		m.selectNode(id)
+	}
+}
+
+// tabSelect selects the next node in tab order, or the previous one when
+// back is set.
+func (m *Model) tabSelect(back bool) {
+	ids := tabOrder(m.Graph)
+	if len(ids) == 0 {
+		return
+	}
+	cur := -1
+	if m.SelectedID != nil {
+		cur = *m.SelectedID
+	}
+	m.selectNode(cycle(ids, cur, back))
+}
+
+// nudge moves the selected node or group by d.
+func (m *Model) nudge(d image.Point) {
+	g := m.Graph
+	switch {
+	case m.SelectedID != nil && g.Node(*m.SelectedID) != nil:
+		n := g.Node(*m.SelectedID)
+		g.MoveNode(n.ID, n.Data.Pos().Add(d), SetPos)
+		m.reveal(graphmodel.BoundsOf(g.Node(n.ID).Data))
+	case m.SelectedGroupID != nil && g.Group(*m.SelectedGroupID) != nil:
+		g.MoveGroup(*m.SelectedGroupID, d, SetPos)
+		m.reveal(g.GroupBounds(*m.SelectedGroupID))
+	}
+}
+
+// freeAt reports whether a node with bounds r would keep at least a cell
+// clear of every visible node.
+func freeAt(g *FlowGraph, r image.Rectangle) bool {
+	for _, n := range g.Nodes() {
+		if !g.Hidden(n.ID) && graphmodel.BoundsOf(n.Data).Overlaps(r.Inset(-1)) {
+			return false
+		}
+	}
+	return true
+}
+
+// addLinked adds a node of the add tool's type next to the selected node
+// — below it, else to its right, else further down — in the same group,
+// connects the selection to it and selects it. With no node selected the
+// new node goes in the middle of the canvas, unconnected.
+func (m *Model) addLinked() {
+	g := m.Graph
+	info := nodeTypeInfo[m.AddNodeType]
+	size := image.Pt(info.W, info.H)
+	var pos image.Point
+	var from *graphmodel.Node[FlowNodeData]
+	if m.SelectedID != nil {
+		from = g.Node(*m.SelectedID)
+	}
+	if from == nil {
+		pos = rectCenter(m.viewportWorld()).Sub(size.Div(2))
+	} else {
+		sel := graphmodel.BoundsOf(from.Data)
+		below := image.Pt(sel.Min.X+(sel.Dx()-size.X)/2, sel.Max.Y+addGapY)
+		right := image.Pt(sel.Max.X+addGapX, sel.Min.Y+(sel.Dy()-size.Y)/2)
+		pos = below
+		if !freeAt(g, image.Rectangle{below, below.Add(size)}) {
+			pos = right
+			for i := 0; !freeAt(g, image.Rectangle{pos, pos.Add(size)}) && i < 20; i++ {
+				pos = below.Add(image.Pt(0, (i+1)*(size.Y+addGapY)))
+			}
+		}
+	}
+
+	id := g.AddNode(FlowNodeData{Type: m.AddNodeType, X: pos.X, Y: pos.Y, Text: "NEW"})
+	if from != nil {
+		if parent := g.ParentOf(from.ID); parent != graphmodel.NoGroup {
+			g.SetParent(id, parent)
+		}
+		addConnection(g, from.ID, "", id, graphmodel.CenterOf(g.Node(from.ID).Data))
+	}
+	m.selectNode(id)
+	m.Status = fmt.Sprintf("added %s #%d", m.AddNodeType, id)
+}
+
+// pickCandidates returns the nodes the selected node can be connected to
+// in pick-target mode, nearest first.
+func (m Model) pickCandidates() []int {
+	if m.ConnectFromID == nil || m.Graph.Node(*m.ConnectFromID) == nil {
+		return nil
+	}
+	g := m.Graph
+	from := graphmodel.CenterOf(g.Node(*m.ConnectFromID).Data)
+	dist := func(id int) int {
+		d := graphmodel.CenterOf(g.Node(id).Data).Sub(from)
+		return d.X*d.X + 4*d.Y*d.Y
+	}
+	var ids []int
+	for _, n := range g.Nodes() {
+		if n.ID != *m.ConnectFromID && !g.Hidden(n.ID) {
+			ids = append(ids, n.ID)
+		}
+	}
+	slices.SortStableFunc(ids, func(a, b int) int { return cmp.Compare(dist(a), dist(b)) })
+	return ids
+}
+
+// startPick enters pick-target mode from the selected node, with the
+// nearest other node as the first candidate.
+func (m *Model) startPick() {
+	if m.SelectedID == nil || m.Graph.Node(*m.SelectedID) == nil {
+		m.Status = "select a node to connect from"
+		return
+	}
+	from := *m.SelectedID
+	m.ConnectFromID = &from
+	m.ConnectFromPort = ""
+	ids := m.pickCandidates()
+	if len(ids) == 0 {
+		m.ConnectFromID = nil
+		m.Status = "no node to connect to"
+		return
+	}
+	m.PickTargetID = &ids[0]
+}
+
+// endPick leaves pick-target mode.
+func (m *Model) endPick() {
+	m.ConnectFromID = nil
+	m.PickTargetID = nil
+}
+
+// pickConnect connects the source to the candidate and leaves
+// pick-target mode.
+func (m *Model) pickConnect() {
+	if m.PickTargetID == nil {
+		m.Status = "not picking a node to connect to"
+		return
+	}
+	from, to := *m.ConnectFromID, *m.PickTargetID
+	addConnection(m.Graph, from, "", to, graphmodel.CenterOf(m.Graph.Node(from).Data))
+	m.endPick()
+	m.Status = fmt.Sprintf("connected #%d → #%d", from, to)
+}
+
+// handlePickKeys processes keys in pick-target mode by the action bound
+// to them: next-node and prev-node cycle the candidates by distance, the
+// nav actions move to the nearest one in a direction, pick-connect
+// connects and cancel leaves the mode. The camera can still be panned
+// and zoomed.
+func (m Model) handlePickKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	a := m.Keymap[msg.String()]
+	if a == nil {
+		return m, nil
+	}
+	target := *m.PickTargetID
+	switch a.Name {
+	case "next-node", "prev-node":
+		target = cycle(m.pickCandidates(), target, a.Name == "prev-node")
+	case "nav-left", "nav-down", "nav-up", "nav-right":
+		from := graphmodel.CenterOf(m.Graph.Node(target).Data)
+		if id, ok := nodeInDir(m.Graph, from, navDirs[a.Name], target, *m.ConnectFromID); ok {
+			target = id
+		}
+	case "cancel":
+		m.endPick()
+		return m, nil
+	case "pick-connect", "quit", "pan-up", "pan-down", "pan-left", "pan-right", "zoom-in", "zoom-out":
+		cmd := a.Run(&m)
+		return m, cmd
+	}
+	if target != *m.PickTargetID {
+		m.PickTargetID = &target
+		m.reveal(graphmodel.BoundsOf(m.Graph.Node(target).Data))
+	}
+	return m, nil
+}
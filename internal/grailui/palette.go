@@ -0,0 +1,201 @@
+package grailui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/wesen/grail/pkg/graphmodel"
+)
+
+// Size of the command palette: its width in cells, border included, and
+// the most results it lists.
+const paletteW, paletteRows = 60, 10
+
+// A paletteItem is a result in the command palette: an action to run or
+// a node to jump to.
+type paletteItem struct {
+	Action *Action // nil for a node
+	NodeID int
+	Label  string
+	Detail string // the action's keys, or the node's code
+	score  int
+}
+
+// openPalette opens the command palette with an empty query.
+func (m *Model) openPalette() {
+	m.PaletteOpen = true
+	m.PaletteQuery = ""
+	m.PaletteSel = 0
+}
+
+// fuzzyMatch reports whether the letters of query appear in s in order,
+// ignoring case, and scores the match. Letters that follow the previous
+// match or start a word score more; gaps between matched letters score
+// less.
+func fuzzyMatch(query, s string) (score int, ok bool) {
+	q := []rune(strings.ToLower(query))
+	r := []rune(strings.ToLower(s))
+	qi, prev := 0, -1
+	for i := 0; i < len(r) && qi < len(q); i++ {
+		if r[i] != q[qi] {
+			continue
+		}
+		score++
+		switch {
+		case prev >= 0 && i == prev+1:
+			score += 4
+		case i == 0 || !unicode.IsLetter(r[i-1]) && !unicode.IsDigit(r[i-1]):
+			score += 3
+		}
+		if prev >= 0 {
+			score -= min(i-prev-1, 3)
+		}
+		prev = i
+		qi++
+	}
+	return score, qi == len(q)
+}
+
+// paletteItems returns the actions and nodes matching the palette's
+// query, best match first. Ties keep actions in registry order ahead of
+// nodes in ID order.
+func (m Model) paletteItems() []paletteItem {
+	var items []paletteItem
+	for _, a := range actions {
+		if a.Name == "palette" {
+			continue
+		}
+		if score, ok := fuzzyMatch(m.PaletteQuery, a.Title); ok {
+			items = append(items, paletteItem{Action: a, Label: a.Title, Detail: m.Keymap.keyLabel(a), score: score})
+		}
+	}
+	for _, n := range m.Graph.Nodes() {
+		label := n.Data.Text
+		if label == "" {
+			label = nodeTypeInfo[n.Data.Type].Label
+		}
+		label = fmt.Sprintf("#%d %s", n.ID, label)
+		score, ok := fuzzyMatch(m.PaletteQuery, label)
+		if cs, cok := fuzzyMatch(m.PaletteQuery, n.Data.Code); cok && (!ok || cs > score) {
+			score, ok = cs, true
+		}
+		if ok {
+			items = append(items, paletteItem{NodeID: n.ID, Label: label, Detail: n.Data.Code, score: score})
+		}
+	}
+	slices.SortStableFunc(items, func(a, b paletteItem) int { return b.score - a.score })
+	return items
+}
+
+// handlePaletteKeys processes keys while the command palette is open:
+// typing filters, up and down choose, enter runs the choice and esc
+// closes the palette.
+func (m Model) handlePaletteKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	items := m.paletteItems()
+	switch key := msg.String(); key {
+	case "esc", "escape", "ctrl+c":
+		m.PaletteOpen = false
+	case "up", "ctrl+k":
+		m.PaletteSel = max(m.PaletteSel-1, 0)
+	case "down", "ctrl+j":
+		m.PaletteSel = max(min(m.PaletteSel+1, len(items)-1), 0)
+	case "enter":
+		m.PaletteOpen = false
+		if m.PaletteSel < 0 || m.PaletteSel >= len(items) {
+			return m, nil
+		}
+		it := items[m.PaletteSel]
+		if it.Action != nil {
+			cmd := it.Action.Run(&m)
+			return m, cmd
+		}
+		m.jumpTo(it.NodeID)
+	case "backspace":
+		if q := []rune(m.PaletteQuery); len(q) > 0 {
+			m.PaletteQuery = string(q[:len(q)-1])
+			m.PaletteSel = 0
+		}
+	case "space":
+		m.PaletteQuery += " "
+		m.PaletteSel = 0
+	default:
+		if text := msg.Key().Text; text != "" {
+			m.PaletteQuery += text
+			m.PaletteSel = 0
+		}
+	}
+	return m, nil
+}
+
+// jumpTo selects a node and pans to it, first expanding any collapsed
+// groups that hide it.
+func (m *Model) jumpTo(id int) {
+	g := m.Graph
+	for g.Hidden(id) {
+		grp := g.Group(g.CollapsedAncestor(id))
+		if grp == nil {
+			break
+		}
+		grp.Collapsed = false
+	}
+	m.selectNode(id)
+	m.panTo(graphmodel.CenterOf(g.Node(id).Data))
+}
+
+// buildPaletteLayer draws the command palette near the top of the
+// screen: the query, then the best matches with the chosen one
+// highlighted. Results scroll to keep the choice in view.
+func buildPaletteLayer(m Model, screenW int) *lipgloss.Layer {
+	bg := c("#0a1510")
+	queryStyle := lipgloss.NewStyle().Foreground(c("#00ffc8")).Background(bg).Bold(true)
+	itemStyle := lipgloss.NewStyle().Foreground(c("#00d4a0")).Background(bg)
+	nodeStyle := lipgloss.NewStyle().Foreground(c("#ddaa44")).Background(bg)
+	detailStyle := lipgloss.NewStyle().Foreground(c("#336655")).Background(bg)
+	inner := paletteW - 4
+
+	items := m.paletteItems()
+	lines := []string{
+		queryStyle.Render(ansi.Truncate("› "+m.PaletteQuery+"▌", inner, "")),
+		detailStyle.Render(strings.Repeat("─", inner)),
+	}
+	first := max(m.PaletteSel-paletteRows+1, 0)
+	for i := first; i < len(items) && i < first+paletteRows; i++ {
+		it := items[i]
+		style := itemStyle
+		if it.Action == nil {
+			style = nodeStyle
+		}
+		detail := detailStyle
+		if i == m.PaletteSel {
+			style = style.Background(selBG).Foreground(selText).Bold(true)
+			detail = detail.Background(selBG)
+		}
+		label := ansi.Truncate(it.Label, inner-2, "…")
+		right := ansi.Truncate(it.Detail, max(inner-2-ansi.StringWidth(label)-2, 0), "…")
+		gap := inner - 2 - ansi.StringWidth(label) - ansi.StringWidth(right)
+		lines = append(lines, style.Render(" "+label+strings.Repeat(" ", gap))+detail.Render(right+" "))
+	}
+	if len(items) == 0 {
+		lines = append(lines, detailStyle.Render(" no matches"))
+	}
+	for len(lines) < paletteRows+2 {
+		lines = append(lines, "")
+	}
+
+	rendered := lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(c("#00d4a0")).
+		BorderBackground(bg).
+		Background(bg).
+		Width(paletteW).
+		Padding(0, 1).
+		Render(strings.Join(lines, "\n"))
+
+	x := max((screenW-lipgloss.Width(rendered))/2, 0)
+	return lipgloss.NewLayer(rendered).X(x).Y(2).Z(100).ID("palette")
+}
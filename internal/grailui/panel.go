@@ -128,17 +128,19 @@ func buildConsolePanelLayer(output []string, x, y, width, height int) *lipgloss.
 	return lipgloss.NewLayer(content).X(x).Y(y).Z(1).ID("panel-console")
 }
 
-// buildHelpPanelLayer renders the static help section.
-func buildHelpPanelLayer(x, y, width, height int) *lipgloss.Layer {
+// buildHelpPanelLayer renders the help section: the hint lines (see
+// Keymap.helpHints), then the action entries (see Keymap.helpEntries)
+// wrapped to fit.
+func buildHelpPanelLayer(hints, entries []string, x, y, width, height int) *lipgloss.Layer {
 	helpLines := []string{
 		panelTitleStyle.Render("❓ HELP"),
 		panelDimStyle.Render(strings.Repeat("─", width-2)),
-		panelTextStyle.Render("  click=select drag=move"),
-		panelTextStyle.Render("  [s]Select [a]Add [c]Connect"),
-		panelTextStyle.Render("  [e]Edit  [d]Delete"),
-		panelTextStyle.Render("  [r]Run [n]Step [g]Auto"),
-		panelTextStyle.Render("  [p]Pause [x]Stop"),
-		panelTextStyle.Render("  Arrows: pan canvas"),
+	}
+	for _, l := range hints {
+		helpLines = append(helpLines, panelTextStyle.Render("  "+l))
+	}
+	for _, l := range wrapEntries(entries, width-2) {
+		helpLines = append(helpLines, panelTextStyle.Render("  "+l))
 	}
 
 	for len(helpLines) < height {
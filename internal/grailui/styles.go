@@ -22,6 +22,10 @@ var (
 		"connector": {border: c("#1a6a4a"), text: c("#00d4a0")},
 	}
 
+	// Group frame colors
+	groupBorder = c("#1a6a4a")
+	groupTitle  = c("#44aa88")
+
 	// Selection / execution override colors
 	selBorder  = c("#00ffee")
 	selText    = c("#00ffee")
@@ -30,10 +34,15 @@ var (
 	execText   = c("#ffee66")
 	execBG     = c("#12120a")
 
-	// Edge colors (used in later tickets)
-	_ = c("#00d4a0") // edgeColor
-	_ = c("#ffcc00") // edgeActColor
-	_ = c("#00ffc8") // edgeLblColor
+	// Diff view colors
+	diffAdded   = c("#44ff44")
+	diffRemoved = c("#ff4455")
+	diffChanged = c("#cc88ff")
+
+	// Edge colors
+	edgeColor    = c("#00d4a0")
+	edgeActColor = c("#ffcc00")
+	edgeLblColor = c("#00ffc8")
 
 	// Chrome colors
 	toolbarColor = c("#00ffc8")
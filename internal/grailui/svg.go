@@ -0,0 +1,246 @@
+package grailui
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+
+	"github.com/wesen/grail/pkg/drawutil"
+	"github.com/wesen/grail/pkg/graphmodel"
+)
+
+// Size of one terminal cell in SVG user units. Cells are twice as tall
+// as they are wide, so charts keep the proportions they have on screen.
+const (
+	svgCellW = 10
+	svgCellH = 20
+)
+
+// svgPt is a point in SVG user units.
+type svgPt struct{ X, Y float64 }
+
+func (p svgPt) String() string { return fmt.Sprintf("%g,%g", p.X, p.Y) }
+
+// svgCell returns the center of a world cell.
+func svgCell(p image.Point) svgPt {
+	return svgPt{(float64(p.X) + 0.5) * svgCellW, (float64(p.Y) + 0.5) * svgCellH}
+}
+
+// svgRect converts a world rectangle of cells to user units.
+func svgRect(r image.Rectangle) (x, y, w, h float64) {
+	return float64(r.Min.X) * svgCellW, float64(r.Min.Y) * svgCellH,
+		float64(r.Dx()) * svgCellW, float64(r.Dy()) * svgCellH
+}
+
+// writeSVG draws the chart as vector shapes: group frames, edges with
+// arrowheads and labels, then nodes shaped by type. Geometry comes from
+// the same node sizes, ports and edge routes as the editor, scaled by
+// the cell size, and colors from the editor's palette.
+func writeSVG(w io.Writer, g *FlowGraph, title string) error {
+	bw := bufio.NewWriter(w)
+	vx, vy, vw, vh := svgRect(chartBounds(g).Inset(-exportMargin))
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="%g %g %g %g" width="%g" height="%g" font-family="'DejaVu Sans Mono',Menlo,Consolas,monospace" font-size="14">
+<title>%s</title>
+<defs>
+<marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse">
+<path d="M0,0 L10,5 L0,10 z" fill="%s"/>
+</marker>
+<marker id="arrow-open" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse">
+<path d="M0,0 L10,5 L0,10" fill="none" stroke="%s" stroke-width="1.5"/>
+</marker>
+</defs>
+<rect x="%g" y="%g" width="%g" height="%g" fill="%s"/>
+`, vx, vy, vw, vh, vw, vh, html.EscapeString(title), hexColor(edgeColor), hexColor(edgeColor), vx, vy, vw, vh, hexColor(colorBG))
+
+	for _, grp := range g.Groups() {
+		if grp.Collapsed || g.GroupHidden(grp.ID) {
+			continue
+		}
+		x, y, w, h := svgRect(g.GroupBounds(grp.ID))
+		fmt.Fprintf(bw, `<rect x="%g" y="%g" width="%g" height="%g" rx="4" fill="none" stroke="%s" stroke-dasharray="6 4"/>`+"\n",
+			x+svgCellW/2, y+svgCellH/2, w-svgCellW, h-svgCellH, hexColor(groupBorder))
+		if grp.Label != "" {
+			svgText(bw, svgPt{x + 3*svgCellW, y + svgCellH/2}, "start", grp.Label, groupTitle, true)
+		}
+	}
+
+	router := newEdgeRouter(g)
+	for _, edge := range g.Edges() {
+		route, ok := router.route(edge, false)
+		if !ok {
+			continue
+		}
+		pts := make([]string, len(route))
+		for i, p := range route {
+			pts[i] = svgCell(p).String()
+		}
+		n := len(route)
+		pts[0] = svgAttach(g, edge.FromID, route[0], route[1]).String()
+		pts[n-1] = svgAttach(g, edge.ToID, route[n-1], route[n-2]).String()
+		svgEdge(bw, strings.Join(pts, " "), edge.Data.stroke())
+	}
+	placed := placeEdgeLabels(g, false)
+	for _, edge := range g.Edges() {
+		if r, ok := placed[edge.ID]; ok {
+			x, y, w, h := svgRect(r)
+			svgText(bw, svgPt{x + w/2, y + h/2}, "middle", edge.Data.Label, edgeLblColor, true)
+		}
+	}
+
+	for _, grp := range g.Groups() {
+		if grp.Collapsed && !g.GroupHidden(grp.ID) {
+			r := g.GroupBounds(grp.ID)
+			svgNode(bw, "process", r, "▸ "+grp.Label, "+", groupBorder, groupTitle)
+		}
+	}
+	for _, node := range g.Nodes() {
+		if g.Hidden(node.ID) {
+			continue
+		}
+		d := node.Data
+		colors := nodeColors[d.Type]
+		svgNode(bw, d.Type, graphmodel.BoundsOf(d), d.Text, nodeTypeInfo[d.Type].Tag, colors.border, colors.text)
+	}
+
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
+
+// svgEdge writes an edge's polyline through points in the edge's line
+// style, with its arrowheads. A double line is a wide stroke with a
+// narrow one in the background color down its middle.
+func svgEdge(w io.Writer, points string, s drawutil.Stroke) {
+	width, dash := "1.5", ""
+	switch s.Line.Kind {
+	case drawutil.LineDashed:
+		dash = ` stroke-dasharray="6 3"`
+	case drawutil.LineDotted:
+		dash = ` stroke-dasharray="2 3"`
+	case drawutil.LineThick:
+		width = "3"
+	case drawutil.LineDouble:
+		width = "4"
+	}
+	markers := ""
+	switch s.Arrow {
+	case drawutil.ArrowTriangle:
+		markers = ` marker-end="url(#arrow)"`
+	case drawutil.ArrowOpen:
+		markers = ` marker-end="url(#arrow-open)"`
+	case drawutil.ArrowBoth:
+		markers = ` marker-start="url(#arrow)" marker-end="url(#arrow)"`
+	}
+	fmt.Fprintf(w, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%s"%s%s/>`+"\n",
+		points, hexColor(edgeColor), width, dash, markers)
+	if s.Line.Kind == drawutil.LineDouble {
+		fmt.Fprintf(w, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.5"/>`+"\n",
+			points, hexColor(colorBG))
+	}
+}
+
+// svgNode draws one node's outline, centered text and type tag.
+func svgNode(w io.Writer, nodeType string, r image.Rectangle, text, tag string, border, fg color.Color) {
+	x, y, wd, ht := svgRect(r)
+	stroke := fmt.Sprintf(`fill="%s" stroke="%s" stroke-width="1.5"`, hexColor(colorBG), hexColor(border))
+	switch nodeType {
+	case "terminal":
+		fmt.Fprintf(w, `<rect x="%g" y="%g" width="%g" height="%g" rx="%g" %s/>`+"\n", x, y, wd, ht, ht/2, stroke)
+	case "decision":
+		d := ht / 2
+		fmt.Fprintf(w, `<polygon points="%v %v %v %v %v %v" %s/>`+"\n",
+			svgPt{x, y + ht/2}, svgPt{x + d, y}, svgPt{x + wd - d, y},
+			svgPt{x + wd, y + ht/2}, svgPt{x + wd - d, y + ht}, svgPt{x + d, y + ht}, stroke)
+	case "io":
+		s := svgSkew(ht)
+		fmt.Fprintf(w, `<polygon points="%v %v %v %v" %s/>`+"\n",
+			svgPt{x + s, y}, svgPt{x + wd, y}, svgPt{x + wd - s, y + ht}, svgPt{x, y + ht}, stroke)
+	case "connector":
+		fmt.Fprintf(w, `<circle cx="%g" cy="%g" r="%g" %s/>`+"\n", x+wd/2, y+ht/2, min(wd, ht)/2, stroke)
+	default:
+		fmt.Fprintf(w, `<rect x="%g" y="%g" width="%g" height="%g" %s/>`+"\n", x, y, wd, ht, stroke)
+	}
+	if text != "" {
+		svgText(w, svgPt{x + wd/2, y + ht/2}, "middle", text, fg, true)
+	}
+	if tag != "" && nodeType != "connector" {
+		svgText(w, svgPt{x + 3*svgCellW, y}, "start", "["+tag+"]", border, false)
+	}
+}
+
+// svgSkew is the horizontal slant of an io node's parallelogram.
+func svgSkew(height float64) float64 { return height / 3 }
+
+// svgAttach returns where an edge meets the outline of node id, given
+// the border cell the route starts or ends at and the next vertex along
+// the route. The point is on the side of the node's rectangle the route
+// leaves through, pulled in to the shape's outline where that does not
+// follow the rectangle.
+func svgAttach(g *FlowGraph, id int, cell, next image.Point) svgPt {
+	r, group, ok := endpointBounds(g, id)
+	if !ok {
+		return svgCell(cell)
+	}
+	nodeType := "process"
+	if group == graphmodel.NoGroup {
+		nodeType = g.Node(id).Data.Type
+	}
+	x, y, wd, ht := svgRect(r)
+	p := svgCell(cell)
+	var inset float64
+	switch nodeType {
+	case "io":
+		inset = svgSkew(ht) / 2
+	case "connector":
+		inset = (wd - min(wd, ht)) / 2
+	}
+	switch svgSide(r, cell, next) {
+	case drawutil.SideTop:
+		p.Y = y
+	case drawutil.SideBottom:
+		p.Y = y + ht
+	case drawutil.SideLeft:
+		p.X = x + inset
+	case drawutil.SideRight:
+		p.X = x + wd - inset
+	}
+	return p
+}
+
+// svgSide returns the side of r that a route leaves through from the
+// border cell at cell toward next: the one it runs straight out of, or
+// else the one nearest to cell. Corner cells of a straight leg need the
+// former, as SideOf would put them on the top or bottom.
+func svgSide(r image.Rectangle, cell, next image.Point) drawutil.Side {
+	switch {
+	case next.Y == cell.Y && next.X >= r.Max.X:
+		return drawutil.SideRight
+	case next.Y == cell.Y && next.X < r.Min.X:
+		return drawutil.SideLeft
+	case next.X == cell.X && next.Y >= r.Max.Y:
+		return drawutil.SideBottom
+	case next.X == cell.X && next.Y < r.Min.Y:
+		return drawutil.SideTop
+	}
+	return drawutil.SideOf(r, cell)
+}
+
+// svgText writes a line of text with a halo in the background color, so
+// that it stays legible over edges and frames.
+func svgText(w io.Writer, p svgPt, anchor, text string, fg color.Color, bold bool) {
+	weight := ""
+	if bold {
+		weight = ` font-weight="bold"`
+	}
+	fmt.Fprintf(w, `<text x="%g" y="%g" text-anchor="%s" dominant-baseline="central" fill="%s"%s stroke="%s" stroke-width="4" paint-order="stroke">%s</text>`+"\n",
+		p.X, p.Y, anchor, hexColor(fg), weight, hexColor(colorBG), html.EscapeString(text))
+}
+
+// hexColor formats c as "#rrggbb".
+func hexColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
@@ -2,23 +2,18 @@ package grailui
 
 import (
 	"image"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	tea "charm.land/bubbletea/v2"
 	"github.com/wesen/grail/internal/flowinterp"
+	"github.com/wesen/grail/pkg/graphmodel"
 )
 
 const panStep = 3
 
-// nodeTypeKeys maps number keys to node types for add mode.
-var nodeTypeKeys = map[string]string{
-	"1": "process",
-	"2": "decision",
-	"3": "terminal",
-	"4": "io",
-	"5": "connector",
-}
-
 // TickMsg drives auto-stepping.
 type TickMsg time.Time
 
@@ -28,24 +23,51 @@ func tickCmd(d time.Duration) tea.Cmd {
 	})
 }
 
-// Update implements tea.Model.
+// Update implements tea.Model. A camera pan started while handling msg
+// (see Model.panTo) is then driven by CamTickMsg until it is done.
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	panning := m.CamTarget != nil
+	next, cmd := m.update(msg)
+	if nm := next.(Model); nm.CamTarget != nil && !panning {
+		cmd = tea.Batch(cmd, camTickCmd())
+	}
+	return next, cmd
+}
+
+// update handles msg for Update.
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.Width = msg.Width
 		m.Height = msg.Height
 
+	case tea.KeyReleaseMsg:
+		// Only reported by terminals with keyboard enhancements
+		if msg.String() == "space" {
+			m.SpaceHeld = false
+		}
+
 	case tea.KeyMsg:
+		// Any other key lets go of space (see SpaceHeld)
+		if msg.String() != "space" {
+			m.SpaceHeld = false
+		}
 		if m.EditOpen {
 			return m.handleEditKeys(msg)
 		}
+		if m.PaletteOpen {
+			return m.handlePaletteKeys(msg)
+		}
 		if m.InputMode {
 			return m.handleInputKeys(msg)
 		}
+		if m.DiffStatus != nil {
+			return m.handleDiffKeys(msg)
+		}
 		return m.handleKeys(msg)
 
 	case tea.MouseMsg:
-		if m.InputMode {
+		if m.InputMode || m.DiffStatus != nil || m.PaletteOpen {
 			return m, nil
 		}
 		canvasRect := m.canvasRect()
@@ -61,80 +83,187 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			m.AutoRunning = false
 		}
+
+	case CamTickMsg:
+		if m.stepCamera() {
+			return m, camTickCmd()
+		}
 	}
 
 	return m, nil
 }
 
-// handleKeys processes keyboard input.
-func (m Model) handleKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	key := msg.String()
-
-	switch key {
-	case "q", "ctrl+c":
+// handleDiffKeys processes keyboard input in the read-only diff view,
+// which only supports the actions for panning, zooming and quitting;
+// cancel quits too.
+func (m Model) handleDiffKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	a := m.Keymap[msg.String()]
+	if a == nil {
+		return m, nil
+	}
+	switch a.Name {
+	case "quit", "cancel":
 		return m, tea.Quit
+	case "pan-up", "pan-down", "pan-left", "pan-right", "zoom-in", "zoom-out":
+		cmd := a.Run(&m)
+		return m, cmd
+	}
+	return m, nil
+}
 
-	// Camera panning
+// pan moves the camera panStep canvas cells in the direction of an
+// arrow key.
+func (m *Model) pan(key string) {
+	m.CamTarget = nil
+	step := panStep * zoomScales[m.Zoom]
+	switch key {
 	case "up":
-		m.CamY -= panStep
+		m.CamY -= step
 	case "down":
-		m.CamY += panStep
+		m.CamY += step
 	case "left":
-		m.CamX -= panStep
+		m.CamX -= step
 	case "right":
-		m.CamX += panStep
-
-	// Tool selection
-	case "s":
-		m.CurrentTool = ToolSelect
-		m.ConnectFromID = nil
-	case "a":
-		m.CurrentTool = ToolAdd
-		m.ConnectFromID = nil
-	case "c":
-		m.CurrentTool = ToolConnect
-		m.ConnectFromID = nil
-
-	// Node type in add mode
-	case "1", "2", "3", "4", "5":
-		if nt, ok := nodeTypeKeys[key]; ok {
-			m.AddNodeType = nt
-			m.CurrentTool = ToolAdd
-		}
+		m.CamX += step
+	}
+}
 
-	// Delete selected
-	case "d", "delete", "backspace":
-		if m.SelectedID != nil {
-			m.Graph.RemoveNode(*m.SelectedID)
-			m.SelectedID = nil
-		}
+// save writes the graph to m.Path and reports the result in the footer.
+func (m *Model) save() {
+	if m.Path == "" {
+		m.Status = "no file to save to (run: grail FILE)"
+		return
+	}
+	if err := SaveGraph(m.Path, m.Graph); err != nil {
+		m.Status = "save failed: " + err.Error()
+		return
+	}
+	m.Status = "saved " + m.Path
+}
 
-	// Escape — cancel current operation
-	case "esc", "escape":
-		m.ConnectFromID = nil
-		m.SelectedID = nil
-		m.CurrentTool = ToolSelect
-
-	// Edit modal
-	case "e":
-		if m.SelectedID != nil {
-			return m.openEditModal()
+// exportSVG writes the chart as SVG next to m.Path, or to chart.svg when
+// there is no file, and reports the result in the footer. A chart file
+// that itself ends in .svg is exported to .export.svg rather than
+// overwritten.
+func (m *Model) exportSVG() {
+	path, title := "chart.svg", "chart"
+	if m.Path != "" {
+		base := strings.TrimSuffix(m.Path, filepath.Ext(m.Path))
+		path = base + ".svg"
+		if path == m.Path {
+			path = base + ".export.svg"
+		}
+		title = filepath.Base(m.Path)
+	}
+	f, err := os.Create(path)
+	if err == nil {
+		err = ExportChart(f, m.Graph, "svg", title)
+		if cerr := f.Close(); err == nil {
+			err = cerr
 		}
+	}
+	if err != nil {
+		m.Status = "export failed: " + err.Error()
+		return
+	}
+	m.Status = "exported " + path
+}
 
-	// Interpreter controls
-	case "r":
-		return m.startProgram()
-	case "n":
-		return m.stepProgram()
-	case "g":
-		return m.autoRun()
-	case "p":
-		m.AutoRunning = false
-	case "x":
-		m.stopProgram()
+// handleKeys processes keyboard input, running the action bound to the
+// key (see Keymap).
+func (m Model) handleKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.PickTargetID != nil {
+		return m.handlePickKeys(msg)
 	}
 
-	return m, nil
+	// Space is held down for space+drag panning, and reserved from the
+	// keymap
+	if msg.String() == "space" {
+		m.SpaceHeld = true
+		return m, nil
+	}
+	a := m.Keymap[msg.String()]
+	if a == nil {
+		return m, nil
+	}
+	cmd := a.Run(&m)
+	return m, cmd
+}
+
+// toggleBraille switches edges between line characters and Braille dots.
+func (m *Model) toggleBraille() {
+	if m.EdgeMode == EdgeBraille {
+		m.EdgeMode = EdgeChars
+	} else {
+		m.EdgeMode = EdgeBraille
+	}
+	m.Status = "edges: " + onOff(m.EdgeMode == EdgeBraille, "braille", "lines")
+}
+
+// selectedEdge returns the selected edge, or nil.
+func (m *Model) selectedEdge() *graphmodel.Edge[FlowEdgeData] {
+	if m.SelectedEdgeID == nil {
+		return nil
+	}
+	return m.Graph.Edge(*m.SelectedEdgeID)
+}
+
+// nameOr returns name, or the default (first) of names when name is
+// empty.
+func nameOr(name string, names []string) string {
+	if name == "" {
+		return names[0]
+	}
+	return name
+}
+
+// onOff returns on if b is set, else off.
+func onOff(b bool, on, off string) string {
+	if b {
+		return on
+	}
+	return off
+}
+
+// groupSelection wraps the selected node or group in a new group, nested
+// where the selection was, and selects the new group.
+func (m *Model) groupSelection() {
+	var groupID int
+	switch {
+	case m.SelectedID != nil:
+		groupID = m.Graph.AddGroup("GROUP", m.Graph.ParentOf(*m.SelectedID))
+		m.Graph.SetParent(*m.SelectedID, groupID)
+	case m.SelectedGroupID != nil:
+		inner := m.Graph.Group(*m.SelectedGroupID)
+		if inner == nil {
+			return
+		}
+		groupID = m.Graph.AddGroup("GROUP", inner.ParentID)
+		m.Graph.SetGroupParent(inner.ID, groupID)
+	default:
+		return
+	}
+	m.SelectedID = nil
+	m.SelectedGroupID = &groupID
+}
+
+// toggleCollapse collapses or expands the selected group, or the group
+// containing the selected node. Collapsing from a node selects the group,
+// since the node is no longer visible.
+func (m *Model) toggleCollapse() {
+	groupID := graphmodel.NoGroup
+	if m.SelectedGroupID != nil {
+		groupID = *m.SelectedGroupID
+	} else if m.SelectedID != nil {
+		groupID = m.Graph.ParentOf(*m.SelectedID)
+	}
+	grp := m.Graph.Group(groupID)
+	if grp == nil {
+		return
+	}
+	grp.Collapsed = !grp.Collapsed
+	m.SelectedID = nil
+	m.SelectedGroupID = &groupID
 }
 
 // handleInputKeys processes keys when waiting for user input.
@@ -245,6 +374,7 @@ func syncInterpreter(m *Model) {
 			m.Interp.Output = append(m.Interp.Output, "⚠ "+m.Interp.Err)
 		}
 	}
+	m.follow()
 }
 
 // canvasRect computes the canvas region rectangle for coordinate transforms.
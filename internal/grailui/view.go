@@ -5,6 +5,7 @@ import (
 
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/wesen/grail/pkg/graphmodel"
 	"github.com/wesen/grail/pkg/tealayout"
 )
 
@@ -63,6 +64,13 @@ func (m Model) View() tea.View {
 	if m.ConnectFromID != nil {
 		toolStr = fmt.Sprintf("CONNECT from #%d → click target", *m.ConnectFromID)
 	}
+	if m.SpaceHeld {
+		toolStr = "PAN — drag to move the canvas"
+	}
+	if m.PickTargetID != nil {
+		toolStr = fmt.Sprintf("CONNECT #%d → #%d [%s]Pick [%s]Link", *m.ConnectFromID, *m.PickTargetID,
+			m.Keymap.navHint(), m.Keymap.keysHint("pick-connect"))
+	}
 
 	// Run state indicator
 	runState := ""
@@ -95,35 +103,62 @@ func (m Model) View() tea.View {
 			selStr = fmt.Sprintf("%d:%s", n.ID, n.Data.Text)
 		}
 	}
+	if m.SelectedGroupID != nil {
+		if grp := m.Graph.Group(*m.SelectedGroupID); grp != nil {
+			selStr = fmt.Sprintf("group %d:%s", grp.ID, grp.Label)
+		}
+	}
+	if m.SelectedEdgeID != nil {
+		if e := m.Graph.Edge(*m.SelectedEdgeID); e != nil {
+			selStr = "edge " + edgeString(*e)
+		}
+	}
 	ftContent := fmt.Sprintf(
 		" Mouse: (%d,%d)  Cam: (%d,%d)  Sel: %s  Nodes: %d",
 		m.MouseX, m.MouseY, m.CamX, m.CamY, selStr, len(m.Graph.Nodes()),
 	)
+	if m.Status != "" {
+		ftContent += "  │ " + m.Status
+	}
 	layers = append(layers,
 		tealayout.FooterLayer(ftContent, m.Width, m.Height-1, ftStyle),
 	)
 
-	// Edge canvas layer (grid + edge lines + connect preview at Z=0)
+	// Edge canvas layer (grid + edge lines + connect preview at Z=0). When
+	// picking a target from the keyboard the preview runs to the candidate
+	// instead of the mouse.
+	previewX, previewY := m.MouseX, m.MouseY
+	if m.PickTargetID != nil {
+		p := m.camera().toCanvas(graphmodel.CenterOf(m.Graph.Node(*m.PickTargetID).Data)).Add(canvasRegion.Rect.Min)
+		previewX, previewY = p.X, p.Y
+	}
 	layers = append(layers,
-		buildEdgeCanvasLayer(m.Graph, m.CamX, m.CamY, canvasRegion.Rect,
-			m.ExecID, m.ConnectFromID, m.MouseX, m.MouseY),
+		buildEdgeCanvasLayer(m.edgeBuf, m.Graph, m.camera(), canvasRegion.Rect,
+			m.ExecID, m.ConnectFromID, m.ConnectFromPort, m.SelectedGroupID, m.SelectedEdgeID, previewX, previewY, m.edgeStyle()),
 	)
 
 	// Node layers (Z=2, on top of edges)
-	nodeLayers := buildNodeLayers(m.Graph, m.CamX, m.CamY, canvasRegion.Rect, m.SelectedID, m.ExecID)
+	nodeLayers := buildNodeLayers(m.Graph, m.camera(), canvasRegion.Rect, m.SelectedID, m.ExecID, m.SelectedGroupID, m.DiffStatus, m.Shapes)
 	layers = append(layers, nodeLayers...)
 
 	// Edge labels (Z=3, on top of nodes)
-	labelLayers := buildEdgeLabelLayers(m.Graph, m.CamX, m.CamY, canvasRegion.Rect)
+	labelLayers := buildEdgeLabelLayers(m.Graph, m.camera(), canvasRegion.Rect, m.Orthogonal)
 	layers = append(layers, labelLayers...)
 
+	// Minimap (Z=5, over the canvas)
+	if m.Minimap {
+		if mm := buildMinimapLayer(m); mm != nil {
+			layers = append(layers, mm)
+		}
+	}
+
 	// Side panel
 	pr := panelRegion.Rect
 	pw := pr.Dx()
 	ph := pr.Dy()
 	if pw > 0 && ph > 0 {
 		varsH := 6
-		helpH := 8
+		helpH := 15
 		consoleH := ph - varsH - helpH
 		if consoleH < 3 {
 			consoleH = 3
@@ -167,7 +202,12 @@ func (m Model) View() tea.View {
 		}
 
 		// Help
-		layers = append(layers, buildHelpPanelLayer(pr.Min.X+1, pr.Min.Y+varsH+consoleH, pw-2, helpH))
+		layers = append(layers, buildHelpPanelLayer(m.Keymap.helpHints(), m.Keymap.helpEntries(), pr.Min.X+1, pr.Min.Y+varsH+consoleH, pw-2, helpH))
+	}
+
+	// Command palette (Z=100, on top of everything)
+	if m.PaletteOpen {
+		layers = append(layers, buildPaletteLayer(m, m.Width))
 	}
 
 	// Edit modal (Z=100, on top of everything)
@@ -185,5 +225,6 @@ func (m Model) View() tea.View {
 	v := tea.NewView(canvas.Render())
 	v.AltScreen = true
 	v.MouseMode = tea.MouseModeAllMotion
+	v.KeyboardEnhancements.ReportEventTypes = true // space+drag ends on release
 	return v
 }
@@ -0,0 +1,524 @@
+package grailui
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/wesen/grail/internal/snapshot"
+	"github.com/wesen/grail/pkg/graphmodel"
+)
+
+// Size of the terminal for view snapshots: wide enough for the whole
+// demo chart next to the side panel.
+const snapW, snapH = 110, 26
+
+// demoModel returns the demo chart sized for snapshots.
+func demoModel() Model {
+	return send(NewModel(), tea.WindowSizeMsg{Width: snapW, Height: snapH})
+}
+
+// loopModel returns loopGraph sized for snapshots.
+func loopModel() Model {
+	m := NewModel()
+	m.Graph = loopGraph()
+	return send(m, tea.WindowSizeMsg{Width: snapW, Height: snapH})
+}
+
+// send passes msgs through Update in order.
+func send(m Model, msgs ...tea.Msg) Model {
+	for _, msg := range msgs {
+		next, _ := m.Update(msg)
+		m = next.(Model)
+	}
+	return m
+}
+
+// key is a press of a printable key.
+func key(s string) tea.Msg {
+	return tea.KeyPressMsg{Code: []rune(s)[0], Text: s}
+}
+
+// click is a left click and release at a screen position.
+func click(x, y int) []tea.Msg {
+	return []tea.Msg{
+		tea.MouseClickMsg{X: x, Y: y, Button: tea.MouseLeft},
+		tea.MouseReleaseMsg{X: x, Y: y, Button: tea.MouseLeft},
+	}
+}
+
+// START is at world (5,1); the canvas starts below the one-row toolbar.
+var startX, startY = 10, 3
+
+// settle runs a camera pan to its end.
+func settle(t *testing.T, m Model) Model {
+	t.Helper()
+	for i := 0; m.CamTarget != nil; i++ {
+		if i == 100 {
+			t.Fatal("camera pan did not end")
+		}
+		m = send(m, CamTickMsg{})
+	}
+	return m
+}
+
+func assertView(t *testing.T, name string, m Model) {
+	t.Helper()
+	snapshot.AssertANSI(t, name, m.View().Content, snapW, snapH)
+}
+
+func TestViewDemo(t *testing.T) {
+	assertView(t, "view_demo", demoModel())
+}
+
+func TestViewSelected(t *testing.T) {
+	m := send(demoModel(), click(startX, startY)...)
+	if m.SelectedID == nil {
+		t.Fatal("click should select START")
+	}
+	assertView(t, "view_selected", m)
+}
+
+func TestViewGroups(t *testing.T) {
+	m := loopModel()
+	assertView(t, "view_group", m)
+
+	// Clicking the frame selects the group; z collapses it
+	m = send(m, click(3, 14)...)
+	if m.SelectedGroupID == nil || *m.SelectedGroupID != 0 {
+		t.Fatalf("clicking the frame should select LOOP, selected %v", m.SelectedGroupID)
+	}
+	m = send(m, key("z"))
+	if !m.Graph.Hidden(3) {
+		t.Fatal("z should collapse LOOP")
+	}
+	assertView(t, "view_group_collapsed", m)
+}
+
+func TestViewExecuting(t *testing.T) {
+	m := send(demoModel(), key("r"), key("n"), key("n"))
+	if m.ExecID == nil {
+		t.Fatal("stepping should set the executing node")
+	}
+	assertView(t, "view_executing", m)
+}
+
+func TestViewConnectPreview(t *testing.T) {
+	m := send(demoModel(), key("c"))
+	m = send(m, click(startX, startY)...)
+	m = send(m, tea.MouseMotionMsg{X: 40, Y: 20})
+	if m.ConnectFromID == nil {
+		t.Fatal("clicking a node with the connect tool should start a connection")
+	}
+	assertView(t, "view_connect", m)
+}
+
+func TestViewSelfLoop(t *testing.T) {
+	m := send(demoModel(), key("c"))
+	m = send(m, click(startX, startY)...)
+	m = send(m, click(startX, startY)...)
+	id := m.Graph.HitTest(image.Pt(5, 1)).ID
+	if !m.Graph.HasEdge(id, id) {
+		t.Fatal("clicking the source node again should add a self-loop")
+	}
+	assertView(t, "view_self_loop", m)
+}
+
+func TestViewEditModal(t *testing.T) {
+	m := send(demoModel(), click(startX, startY)...)
+	m = send(m, key("e"))
+	if !m.EditOpen {
+		t.Fatal("e should open the edit modal")
+	}
+	assertView(t, "view_edit", m)
+}
+
+func TestViewBraille(t *testing.T) {
+	m := send(demoModel(), key("b"))
+	if m.EdgeMode != EdgeBraille {
+		t.Fatal("b should switch edges to Braille")
+	}
+	assertView(t, "view_braille", m)
+}
+
+func TestViewOrthogonalHops(t *testing.T) {
+	m := send(demoModel(), key("o"), key("O"))
+	if !m.Orthogonal || !m.Hops {
+		t.Fatal("o and O should turn on orthogonal routing and hops")
+	}
+	assertView(t, "view_orthogonal", m)
+}
+
+func TestViewShapes(t *testing.T) {
+	m := send(demoModel(), key("v"))
+	if !m.Shapes {
+		t.Fatal("v should draw nodes as shapes")
+	}
+	assertView(t, "view_shapes", m)
+}
+
+func TestViewEdgeStyle(t *testing.T) {
+	// The N edge from the decision runs along world row 10
+	m := send(demoModel(), click(30, 11)...)
+	if m.SelectedEdgeID == nil {
+		t.Fatal("clicking an edge should select it")
+	}
+	m = send(m, key("w"), key("W"), key("W"))
+	e := m.Graph.Edge(*m.SelectedEdgeID)
+	if e.Data.Line != "dashed" || e.Data.Arrow != "none" {
+		t.Fatalf("w and W should cycle the style, got line %q arrow %q", e.Data.Line, e.Data.Arrow)
+	}
+	assertView(t, "view_edge_style", m)
+}
+
+func TestViewZoom(t *testing.T) {
+	m := send(demoModel(), key("-"))
+	if m.Zoom != ZoomCompact {
+		t.Fatal("- should zoom out")
+	}
+	assertView(t, "view_zoom_compact", m)
+	m = send(m, key("-"))
+	assertView(t, "view_zoom_glyphs", m)
+	m = send(m, key("+"), key("+"))
+	if m.Zoom != ZoomFull || m.CamX != 0 || m.CamY != 0 {
+		t.Errorf("zooming back in should restore the view, got zoom %d cam (%d,%d)", m.Zoom, m.CamX, m.CamY)
+	}
+}
+
+func TestViewZoomClick(t *testing.T) {
+	// World (0,0), under the cursor, stays in the top-left canvas cell
+	m := send(demoModel(), tea.MouseWheelMsg{X: 0, Y: 1, Button: tea.MouseWheelDown, Mod: tea.ModCtrl})
+	if m.Zoom != ZoomCompact || m.CamX != -1 || m.CamY != -1 {
+		t.Fatalf("ctrl+wheel should zoom out around the cursor, got zoom %d cam (%d,%d)", m.Zoom, m.CamX, m.CamY)
+	}
+	// START (world (5,1)–(27,4)) is drawn compact on canvas row 1
+	m = send(m, click(5, 2)...)
+	if m.SelectedID == nil || m.Graph.Node(*m.SelectedID).Data.Text != "START" {
+		t.Fatal("clicking a compact node should select it")
+	}
+}
+
+func TestWheelPan(t *testing.T) {
+	m := send(demoModel(), tea.MouseWheelMsg{X: 20, Y: 10, Button: tea.MouseWheelDown})
+	if m.CamX != 0 || m.CamY != panStep || m.Zoom != ZoomFull {
+		t.Errorf("wheel should scroll down, got cam (%d,%d) zoom %d", m.CamX, m.CamY, m.Zoom)
+	}
+	m = send(m, tea.MouseWheelMsg{X: 20, Y: 10, Button: tea.MouseWheelDown, Mod: tea.ModShift})
+	if m.CamX != panStep || m.CamY != panStep {
+		t.Errorf("shift+wheel should scroll sideways, got cam (%d,%d)", m.CamX, m.CamY)
+	}
+}
+
+func TestPanDrag(t *testing.T) {
+	m := demoModel()
+	before := m.camera().toWorld(image.Pt(startX, startY-1))
+	m = send(m,
+		tea.MouseClickMsg{X: startX, Y: startY, Button: tea.MouseMiddle},
+		tea.MouseMotionMsg{X: startX + 7, Y: startY + 2, Button: tea.MouseMiddle},
+	)
+	if m.CamX != -7 || m.CamY != -2 {
+		t.Fatalf("middle drag should move the camera with the mouse, got cam (%d,%d)", m.CamX, m.CamY)
+	}
+	if got := m.camera().toWorld(image.Pt(startX+7, startY+1)); got != before {
+		t.Errorf("world point under the mouse moved from %v to %v", before, got)
+	}
+	m = send(m, tea.MouseReleaseMsg{X: startX + 7, Y: startY + 2, Button: tea.MouseMiddle})
+	if m.Panning {
+		t.Error("releasing the button should end the pan")
+	}
+
+	// Space+drag on a node pans instead of moving the node
+	node := *m.Graph.HitTest(image.Pt(5, 1))
+	m = send(m, tea.KeyPressMsg{Code: tea.KeySpace, Text: " "})
+	m = send(m,
+		tea.MouseClickMsg{X: startX + 7, Y: startY + 2, Button: tea.MouseLeft},
+		tea.MouseMotionMsg{X: startX + 10, Y: startY + 2, Button: tea.MouseLeft},
+		tea.MouseReleaseMsg{X: startX + 10, Y: startY + 2, Button: tea.MouseLeft},
+	)
+	if m.CamX != -10 || m.Graph.Node(node.ID).Data != node.Data || m.SelectedID != nil {
+		t.Errorf("space+drag should pan, got cam x %d, node %+v", m.CamX, m.Graph.Node(node.ID).Data)
+	}
+}
+
+func TestSpaceLetGo(t *testing.T) {
+	// Without key release events, space is let go of by the next other
+	// key or by a click that does not pan
+	space := tea.KeyPressMsg{Code: tea.KeySpace, Text: " "}
+	m := send(demoModel(), space)
+	if !strings.Contains(m.View().Content, "PAN — drag") {
+		t.Error("the toolbar should show that space+drag is armed")
+	}
+	m = send(m, key("o"))
+	if m.SpaceHeld {
+		t.Error("another key should let go of space")
+	}
+	m = send(m, space, tea.MouseClickMsg{X: startX, Y: startY, Button: tea.MouseRight})
+	if m.SpaceHeld {
+		t.Error("a right click should let go of space")
+	}
+	m = send(m, click(startX, startY)...)
+	if m.Panning || m.SelectedID == nil {
+		t.Error("a later left click should select, not pan")
+	}
+}
+
+func TestViewMinimap(t *testing.T) {
+	m := send(demoModel(), key("M"))
+	if !m.Minimap {
+		t.Fatal("M should show the minimap")
+	}
+	assertView(t, "view_minimap", m)
+
+	// Clicking the map's top-left corner centres the camera on the
+	// top-left of the mapped world
+	r, cam, ok := m.minimapLayout()
+	if !ok {
+		t.Fatal("the snapshot canvas should have room for the minimap")
+	}
+	m = send(m, click(r.Min.X+1, r.Min.Y+1)...)
+	want := cam.toWorld(image.Point{})
+	canvas := m.canvasRect()
+	if got := image.Pt(m.CamX, m.CamY).Add(image.Pt(canvas.Dx()/2, canvas.Dy()/2)); got != want {
+		t.Errorf("camera centre = %v, want %v", got, want)
+	}
+	if m.SelectedID != nil {
+		t.Error("clicking the minimap should not select what is under it")
+	}
+}
+
+func TestFollowExecution(t *testing.T) {
+	m := demoModel()
+	for range 30 {
+		m = send(m, key("right"))
+	}
+	m = send(m, key("f"), key("r"))
+	if m.CamTarget == nil {
+		t.Fatal("follow should pan to the executing node when it is off the canvas")
+	}
+	m = settle(t, m)
+	start := graphmodel.BoundsOf(m.Graph.Node(*m.ExecID).Data)
+	if !start.In(m.viewportWorld()) {
+		t.Errorf("executing node %v should be in view %v", start, m.viewportWorld())
+	}
+	m = send(m, key("n"))
+	if m.CamTarget != nil {
+		t.Error("follow should not pan while the executing node is well in view")
+	}
+}
+
+func TestFitChart(t *testing.T) {
+	m := demoModel()
+	for range 30 {
+		m = send(m, key("down"))
+	}
+	m = send(m, key("F"))
+	if m.Zoom != ZoomFull {
+		t.Errorf("the demo chart should fit at full detail, got zoom %d", m.Zoom)
+	}
+	if !chartBounds(m.Graph).In(m.viewportWorld()) {
+		t.Errorf("chart %v should be in view %v", chartBounds(m.Graph), m.viewportWorld())
+	}
+}
+
+func TestCenterSelection(t *testing.T) {
+	m := send(demoModel(), click(startX, startY)...)
+	m = settle(t, send(m, key(".")))
+	canvas := m.canvasRect()
+	centre := image.Pt(m.CamX+canvas.Dx()/2, m.CamY+canvas.Dy()/2)
+	if want := graphmodel.CenterOf(m.Graph.Node(*m.SelectedID).Data); centre != want {
+		t.Errorf("canvas centre = %v, want the selected node's centre %v", centre, want)
+	}
+}
+
+// press is a press of a named key, with modifiers.
+func press(code rune, mod tea.KeyMod) tea.Msg {
+	return tea.KeyPressMsg{Code: code, Mod: mod}
+}
+
+func TestKeyboardNavigation(t *testing.T) {
+	m := send(demoModel(), click(startX, startY)...)
+	steps := []struct {
+		msg  tea.Msg
+		want int
+	}{
+		{key("j"), 1},             // START → INIT below
+		{key("j"), 2},             // → the decision
+		{key("l"), 4},             // → the connector, nearer than PRINT SUM
+		{key("h"), 2},             // back to the decision
+		{key("k"), 1},             // → INIT
+		{press(tea.KeyTab, 0), 2}, // reading order: INIT, decision, PRINT SUM
+		{press(tea.KeyTab, 0), 5},
+		{press(tea.KeyTab, tea.ModShift), 2}, // and back
+	}
+	for i, s := range steps {
+		m = send(m, s.msg)
+		if m.SelectedID == nil {
+			t.Fatalf("step %d: nothing selected, want #%d", i, s.want)
+		}
+		if *m.SelectedID != s.want {
+			t.Fatalf("step %d: selected #%d, want #%d", i, *m.SelectedID, s.want)
+		}
+	}
+}
+
+func TestNudgeSelection(t *testing.T) {
+	m := send(demoModel(), click(startX, startY)...)
+	m = send(m, press(tea.KeyDown, tea.ModShift), press(tea.KeyRight, tea.ModShift), press(tea.KeyRight, tea.ModShift))
+	if got, want := m.Graph.Node(0).Data.Pos(), image.Pt(7, 2); got != want {
+		t.Errorf("START at %v after shift+arrows, want %v", got, want)
+	}
+}
+
+func TestViewAddLinked(t *testing.T) {
+	// Below START is INIT, so the new node goes to its right
+	m := send(demoModel(), click(startX, startY)...)
+	m = send(m, key("A"))
+	id := *m.SelectedID
+	if id == 0 || !m.Graph.HasEdge(0, id) {
+		t.Fatalf("A should add, select and connect a node from START; selected #%d", id)
+	}
+	if got, want := graphmodel.BoundsOf(m.Graph.Node(id).Data).Min, image.Pt(31, 1); got != want {
+		t.Errorf("new node at %v, want %v", got, want)
+	}
+	assertView(t, "view_add_linked", m)
+
+	// Added from the loop body, it joins the loop's group
+	m = send(loopModel(), click(startX, startY)...)
+	m = send(m, key("j"), key("j"), key("j"))
+	if *m.SelectedID != 3 {
+		t.Fatalf("selected #%d, want ACCUMULATE", *m.SelectedID)
+	}
+	m = send(m, key("A"))
+	if got := m.Graph.ParentOf(*m.SelectedID); got != m.Graph.ParentOf(3) {
+		t.Errorf("node added from ACCUMULATE is in group %d, want %d", got, m.Graph.ParentOf(3))
+	}
+}
+
+func TestViewPickConnect(t *testing.T) {
+	m := send(demoModel(), click(startX, startY)...)
+	m = send(m, key("C"))
+	if m.PickTargetID == nil || *m.PickTargetID != 1 {
+		t.Fatalf("C should pick the nearest node, INIT, first; got %v", m.PickTargetID)
+	}
+	m = send(m, press(tea.KeyTab, 0))
+	if *m.PickTargetID != 2 {
+		t.Fatalf("tab should pick the decision next; got #%d", *m.PickTargetID)
+	}
+	assertView(t, "view_pick", m)
+
+	// Panning leaves the camera where it is put, even with the candidate
+	// out of view
+	for range 6 {
+		m = send(m, press(tea.KeyRight, 0))
+	}
+	if m.CamTarget != nil {
+		t.Errorf("panning while picking should not pan back to the candidate, camera heads for %v", *m.CamTarget)
+	}
+
+	m = send(m, press(tea.KeyEnter, 0))
+	if m.PickTargetID != nil || m.ConnectFromID != nil {
+		t.Error("enter should end picking")
+	}
+	if !m.Graph.HasEdge(0, 2) {
+		t.Error("enter should connect START to the decision")
+	}
+}
+
+func TestPickPassesOverSource(t *testing.T) {
+	// Connect from INIT; from START, the node below is INIT itself, so j
+	// goes on to the decision beneath it
+	m := send(demoModel(), click(startX, startY)...)
+	m = send(m, key("j"), key("C"))
+	for *m.PickTargetID != 0 {
+		m = send(m, press(tea.KeyTab, 0))
+	}
+	m = send(m, key("j"))
+	if *m.PickTargetID != 2 {
+		t.Errorf("j from START should pass over INIT to the decision, picked #%d", *m.PickTargetID)
+	}
+}
+
+func TestPickReboundKeys(t *testing.T) {
+	km, err := NewKeymap(KeyConfig{"nav-down": {"n"}, "pan-right": {"L"}, "pick-connect": {"y"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := demoModel()
+	m.Keymap = km
+	m = send(m, click(startX, startY)...)
+	m = send(m, key("C"), key("n"))
+	if *m.PickTargetID != 2 {
+		t.Fatalf("n, rebound to nav-down, should pick the decision below INIT; picked #%d", *m.PickTargetID)
+	}
+	camX := m.CamX
+	m = send(m, key("L"))
+	if m.CamX <= camX || m.PickTargetID == nil {
+		t.Errorf("L, rebound to pan-right, should pan while picking")
+	}
+	m = send(m, key("y"))
+	if m.PickTargetID != nil || !m.Graph.HasEdge(0, 2) {
+		t.Error("y, rebound to pick-connect, should connect START to the decision")
+	}
+}
+
+// typeText types s into the model a rune at a time.
+func typeText(m Model, s string) Model {
+	for _, r := range s {
+		m = send(m, key(string(r)))
+	}
+	return m
+}
+
+func TestViewPalette(t *testing.T) {
+	m := send(demoModel(), tea.KeyPressMsg{Code: 'p', Mod: tea.ModCtrl})
+	if !m.PaletteOpen {
+		t.Fatal("ctrl+p should open the palette")
+	}
+	m = typeText(m, "tog")
+	assertView(t, "view_palette", m)
+
+	// Choosing a command runs it
+	m = send(m, press(tea.KeyDown, 0), press(tea.KeyEnter, 0))
+	if m.PaletteOpen || !m.Orthogonal {
+		t.Errorf("the second match, orthogonal routing, should be on and the palette closed")
+	}
+}
+
+func TestPaletteNoMatches(t *testing.T) {
+	m := typeText(send(demoModel(), key(":")), "zzzzqqq")
+	m = send(m, press(tea.KeyDown, 0), press(tea.KeyUp, 0), press(tea.KeyDown, 0))
+	if m.PaletteSel != 0 {
+		t.Errorf("with no matches the choice should stay at 0, got %d", m.PaletteSel)
+	}
+	m = send(m, press(tea.KeyEnter, 0))
+	if m.PaletteOpen {
+		t.Error("enter with no matches should just close the palette")
+	}
+}
+
+func TestPaletteJumpToNode(t *testing.T) {
+	// Hide ACCUMULATE in its collapsed group, then find it by its code
+	m := send(loopModel(), click(startX, startY)...)
+	m = send(m, key("j"), key("j"), key("z"))
+	if !m.Graph.Hidden(3) {
+		t.Fatal("collapsing the loop should hide ACCUMULATE")
+	}
+	m = typeText(send(m, key(":")), "sum + i")
+	m = settle(t, send(m, press(tea.KeyEnter, 0)))
+	if m.SelectedID == nil || *m.SelectedID != 3 {
+		t.Fatalf("selected %v, want ACCUMULATE", m.SelectedID)
+	}
+	if m.Graph.Hidden(3) {
+		t.Error("jumping to a hidden node should expand its group")
+	}
+	canvas := m.canvasRect()
+	centre := image.Pt(m.CamX+canvas.Dx()/2, m.CamY+canvas.Dy()/2)
+	if want := graphmodel.CenterOf(m.Graph.Node(3).Data); centre != want {
+		t.Errorf("canvas centre = %v, want ACCUMULATE's centre %v", centre, want)
+	}
+}
+
+func TestExportChartSnapshot(t *testing.T) {
+	snapshot.AssertBuffer(t, "export_demo", chartBuffer(MakeInitialGraph()))
+}
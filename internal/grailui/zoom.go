@@ -0,0 +1,252 @@
+package grailui
+
+import (
+	"fmt"
+	"image"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/wesen/grail/pkg/drawutil"
+	"github.com/wesen/grail/pkg/graphmodel"
+)
+
+// Zoom levels, from full detail out. Zoomed out, nodes are drawn with
+// less detail: compact one-row boxes, then single glyphs.
+const (
+	ZoomFull    = iota // 1:1, nodes as boxes with labels
+	ZoomCompact        // 1:2, nodes as one-row boxes
+	ZoomGlyph          // 1:4, nodes as one glyph each
+)
+
+// zoomScales is the number of world cells across one canvas cell at each
+// zoom level.
+var zoomScales = []int{1, 2, 4}
+
+// zoomNames names the zoom levels in the footer.
+var zoomNames = []string{"full", "compact", "glyphs"}
+
+// camera maps between world and canvas coordinates. Canvas coordinates
+// are relative to the top-left cell of the canvas.
+type camera struct {
+	Pos   image.Point // world position of the canvas's top-left cell
+	Scale int         // world cells per canvas cell, across and down
+}
+
+// toCanvas returns the canvas cell that world point p falls in.
+func (c camera) toCanvas(p image.Point) image.Point {
+	d := p.Sub(c.Pos)
+	return image.Pt(drawutil.FloorDiv(d.X, c.Scale), drawutil.FloorDiv(d.Y, c.Scale))
+}
+
+// toWorld returns the world point in the middle of the block of world
+// cells that canvas cell p covers. At 1:1 that is the cell itself.
+func (c camera) toWorld(p image.Point) image.Point {
+	return c.Pos.Add(p.Mul(c.Scale)).Add(image.Pt(c.Scale/2, c.Scale/2))
+}
+
+// rect returns the canvas cells that world rectangle r touches.
+func (c camera) rect(r image.Rectangle) image.Rectangle {
+	if r.Empty() {
+		return image.Rectangle{}
+	}
+	return image.Rectangle{c.toCanvas(r.Min), c.toCanvas(r.Max.Sub(image.Pt(1, 1))).Add(image.Pt(1, 1))}
+}
+
+// route maps the vertices of a world route onto the canvas, dropping
+// those that land on the same cell as the vertex before them.
+func (c camera) route(route []image.Point) []image.Point {
+	out := make([]image.Point, 0, len(route))
+	for _, p := range route {
+		p = c.toCanvas(p)
+		if len(out) == 0 || out[len(out)-1] != p {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// nodeCells returns the canvas cells a node or collapsed group with world
+// bounds r is drawn in: its whole box at full detail, one row through
+// its middle when compact, and the cell of its centre as a glyph.
+func (c camera) nodeCells(r image.Rectangle) image.Rectangle {
+	switch {
+	case c.Scale >= zoomScales[ZoomGlyph]:
+		p := c.toCanvas(rectCenter(r))
+		return image.Rectangle{p, p.Add(image.Pt(1, 1))}
+	case c.Scale > 1:
+		cells := c.rect(r)
+		y := c.toCanvas(rectCenter(r)).Y
+		return image.Rect(cells.Min.X, y, cells.Max.X, y+1)
+	}
+	return c.rect(r)
+}
+
+// camera returns the mapping between the world and the canvas.
+func (m Model) camera() camera {
+	return camera{Pos: image.Pt(m.CamX, m.CamY), Scale: zoomScales[m.Zoom]}
+}
+
+// zoomTo switches to zoom level, clamped to the levels there are, keeping
+// the world point under canvas cell at where it is.
+func (m *Model) zoomTo(level int, at image.Point) {
+	level = clamp(level, ZoomFull, len(zoomScales)-1)
+	if level == m.Zoom {
+		return
+	}
+	world := m.camera().toWorld(at)
+	m.CamTarget = nil
+	m.Zoom = level
+	s := zoomScales[level]
+	m.CamX = world.X - at.X*s - s/2
+	m.CamY = world.Y - at.Y*s - s/2
+	m.Status = fmt.Sprintf("zoom: %s (1:%d)", zoomNames[level], s)
+}
+
+// zoomAnchor returns the canvas cell keyboard zooming centres on: the
+// one under the mouse, or the middle of the canvas when the mouse is
+// elsewhere.
+func (m Model) zoomAnchor() image.Point {
+	canvas := m.canvasRect()
+	if mouse := image.Pt(m.MouseX, m.MouseY); mouse.In(canvas) {
+		return mouse.Sub(canvas.Min)
+	}
+	return image.Pt(canvas.Dx()/2, canvas.Dy()/2)
+}
+
+// pick returns the world point a click on canvas cell p stands for. At
+// 1:1 that is the cell itself. Zoomed out, a cell covers a block of world
+// cells, and the nodes and group frames drawn in it need not overlap the
+// middle of the block, so the point is moved onto whatever is drawn
+// there: the topmost node or collapsed group, else a group frame.
+func (m Model) pick(p image.Point) image.Point {
+	cam := m.camera()
+	world := cam.toWorld(p)
+	if cam.Scale == 1 {
+		return world
+	}
+	g := m.Graph
+	nodes := g.Nodes()
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if g.Hidden(nodes[i].ID) {
+			continue
+		}
+		r := graphmodel.BoundsOf(nodes[i].Data)
+		if p.In(cam.nodeCells(r)) {
+			return clampTo(world, r)
+		}
+	}
+	groups := g.Groups()
+	for i := len(groups) - 1; i >= 0; i-- {
+		grp := groups[i]
+		if g.GroupHidden(grp.ID) {
+			continue
+		}
+		r := g.GroupBounds(grp.ID)
+		if grp.Collapsed {
+			if p.In(cam.nodeCells(r)) {
+				return clampTo(world, r)
+			}
+			continue
+		}
+		if cells := cam.rect(r); p.In(cells) {
+			q := clampTo(world, r)
+			switch {
+			case p.X == cells.Min.X:
+				q.X = r.Min.X
+			case p.X == cells.Max.X-1:
+				q.X = r.Max.X - 1
+			case p.Y == cells.Min.Y:
+				q.Y = r.Min.Y
+			case p.Y == cells.Max.Y-1:
+				q.Y = r.Max.Y - 1
+			default:
+				continue
+			}
+			return q
+		}
+	}
+	return world
+}
+
+// clampTo returns the point of r nearest to p.
+func clampTo(p image.Point, r image.Rectangle) image.Point {
+	return image.Pt(clamp(p.X, r.Min.X, r.Max.X-1), clamp(p.Y, r.Min.Y, r.Max.Y-1))
+}
+
+// buildZoomedNodeLayers is buildNodeLayers when zoomed out: each visible
+// node and collapsed group is a one-row box holding its label when
+// compact, and a glyph of its type at the smallest zoom.
+func buildZoomedNodeLayers(g *FlowGraph, cam camera, viewport image.Rectangle,
+	selectedID, execID, selectedGroupID *int, diff map[int]graphmodel.Change) []*lipgloss.Layer {
+
+	var layers []*lipgloss.Layer
+	add := func(id string, r image.Rectangle, shape drawutil.Shape, glyph rune, text string, bc, tc lipgloss.Style) {
+		cells := cam.nodeCells(r).Add(viewport.Min)
+		if !cells.Overlaps(viewport) {
+			return
+		}
+		var rendered string
+		if cam.Scale >= zoomScales[ZoomGlyph] || cells.Dx() < 3 {
+			rendered = bc.Bold(true).Render(string(glyph))
+		} else {
+			open, close := compactEnds(shape)
+			label := ansi.Truncate(text, cells.Dx()-2, "")
+			rendered = bc.Render(string(open)) +
+				tc.Bold(true).Width(cells.Dx()-2).AlignHorizontal(lipgloss.Center).Render(label) +
+				bc.Render(string(close))
+		}
+		layers = append(layers, lipgloss.NewLayer(rendered).X(cells.Min.X).Y(cells.Min.Y).Z(2).ID(id))
+	}
+
+	for _, grp := range g.Groups() {
+		if !grp.Collapsed || g.GroupHidden(grp.ID) {
+			continue
+		}
+		bc, tc, bg := groupBorder, groupTitle, colorBG
+		if selectedGroupID != nil && grp.ID == *selectedGroupID {
+			bc, tc, bg = selBorder, selText, selBG
+		}
+		if execID != nil && g.CollapsedAncestor(*execID) == grp.ID {
+			bc, tc, bg = execBorder, execText, execBG
+		}
+		base := lipgloss.NewStyle().Background(bg)
+		add(fmt.Sprintf("group-%d", grp.ID), g.GroupBounds(grp.ID), drawutil.ShapeBox, '▣', "▸ "+grp.Label,
+			base.Foreground(bc), base.Foreground(tc))
+	}
+
+	for _, node := range g.Nodes() {
+		if g.Hidden(node.ID) {
+			continue
+		}
+		d := node.Data
+		info := nodeTypeInfo[d.Type]
+		bc, tc, bg := nodeColors[d.Type].border, nodeColors[d.Type].text, colorBG
+		if selectedID != nil && node.ID == *selectedID {
+			bc, tc, bg = selBorder, selText, selBG
+		}
+		if execID != nil && node.ID == *execID {
+			bc, tc, bg = execBorder, execText, execBG
+		}
+		if ch, ok := diff[node.ID]; ok {
+			bc, tc = diffColor(ch), diffColor(ch)
+		}
+		base := lipgloss.NewStyle().Background(bg)
+		add(fmt.Sprintf("node-%d", node.ID), graphmodel.BoundsOf(d), info.Shape, info.Glyph, d.Text,
+			base.Foreground(bc), base.Foreground(tc))
+	}
+	return layers
+}
+
+// compactEnds returns the characters that close off a compact box on the
+// left and right, hinting at the node's shape.
+func compactEnds(shape drawutil.Shape) (open, close rune) {
+	switch shape {
+	case drawutil.ShapeRounded, drawutil.ShapeCircle:
+		return '(', ')'
+	case drawutil.ShapeDiamond, drawutil.ShapeHexagon:
+		return '<', '>'
+	case drawutil.ShapeParallelogram:
+		return '/', '/'
+	}
+	return '[', ']'
+}
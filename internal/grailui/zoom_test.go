@@ -0,0 +1,24 @@
+package grailui
+
+import (
+	"image"
+	"testing"
+)
+
+func TestCameraMapping(t *testing.T) {
+	cam := camera{Pos: image.Pt(-3, 5), Scale: 4}
+	for _, p := range []image.Point{{0, 0}, {7, 2}, {-2, -1}} {
+		if got := cam.toCanvas(cam.toWorld(p)); got != p {
+			t.Errorf("toCanvas(toWorld(%v)) = %v", p, got)
+		}
+	}
+	if got := cam.toCanvas(image.Pt(-4, 4)); got != image.Pt(-1, -1) {
+		t.Errorf("points left of and above the camera should round down, got %v", got)
+	}
+	if got := cam.rect(image.Rect(-3, 5, 6, 6)); got != image.Rect(0, 0, 3, 1) {
+		t.Errorf("rect = %v", got)
+	}
+	if got := cam.route([]image.Point{{-3, 5}, {-2, 5}, {5, 5}}); len(got) != 2 {
+		t.Errorf("route should drop vertices on the same cell, got %v", got)
+	}
+}
@@ -0,0 +1,161 @@
+// Package snapshot compares rendered terminal output against golden files.
+//
+// A snapshot is a stable text form of a cellbuf.Buffer: the characters,
+// then the same grid with one letter per cell naming its style, then a
+// legend that spells out each letter's StyleKey, colors and attributes.
+// Styled strings, such as a bubbletea view, are parsed into a buffer of a
+// fixed size first.
+//
+// Golden files live in testdata/<name>.golden next to the test. When a
+// rendering change is intended, rewrite them with
+//
+//	go test ./internal/grailui -update
+//
+// and review the diff.
+package snapshot
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wesen/grail/pkg/cellbuf"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// letters name styles in the order they first appear.
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// styleID is what distinguishes two cells' styles.
+type styleID struct {
+	key  cellbuf.StyleKey
+	rich cellbuf.RichStyle
+}
+
+// Encode returns the snapshot text of b. Rows are framed with '|' so
+// that trailing spaces survive editors. Styles beyond the 62 that have
+// letters are all shown as '?' and listed under it.
+func Encode(b *cellbuf.Buffer) string {
+	var text, styles strings.Builder
+	names := make(map[styleID]byte)
+	var legend []string
+	for _, row := range b.Cells {
+		text.WriteByte('|')
+		styles.WriteByte('|')
+		for _, c := range row {
+			id := styleID{c.Style, c.Rich}
+			name, ok := names[id]
+			if !ok {
+				name = '?'
+				if len(names) < len(letters) {
+					name = letters[len(names)]
+				}
+				names[id] = name
+				legend = append(legend, fmt.Sprintf("%c %s", name, describe(id)))
+			}
+			styles.WriteByte(name)
+			switch {
+			case c.Cont:
+			case c.Ch == cellbuf.Transparent:
+				text.WriteByte(' ')
+			default:
+				text.WriteString(c.Text())
+			}
+		}
+		text.WriteString("|\n")
+		styles.WriteString("|\n")
+	}
+	return fmt.Sprintf("-- text %dx%d --\n%s-- styles --\n%s-- legend --\n%s\n",
+		b.W, b.H, text.String(), styles.String(), strings.Join(legend, "\n"))
+}
+
+// EncodeANSI returns the snapshot text of a styled string drawn into a
+// w×h buffer; anything outside it is clipped.
+func EncodeANSI(s string, w, h int) string {
+	b := cellbuf.New(w, h, 0)
+	b.SetANSI(0, 0, s, 0)
+	return Encode(b)
+}
+
+// describe spells out a style for the legend.
+func describe(id styleID) string {
+	parts := []string{fmt.Sprintf("key=%d", id.key)}
+	if id.rich.Fg.IsSet() {
+		parts = append(parts, "fg="+id.rich.Fg.String())
+	}
+	if id.rich.Bg.IsSet() {
+		parts = append(parts, "bg="+id.rich.Bg.String())
+	}
+	for _, a := range []struct {
+		attr cellbuf.Attrs
+		name string
+	}{
+		{cellbuf.Bold, "bold"},
+		{cellbuf.Italic, "italic"},
+		{cellbuf.Underline, "underline"},
+		{cellbuf.Reverse, "reverse"},
+	} {
+		if id.rich.Attrs&a.attr != 0 {
+			parts = append(parts, a.name)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Assert compares got with testdata/<name>.golden, or rewrites the file
+// when the test runs with -update.
+func Assert(t testing.TB, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s does not match the golden file (run with -update to accept):\n%s",
+			name, firstDiff(string(want), got))
+	}
+}
+
+// AssertBuffer is Assert for the snapshot of a buffer.
+func AssertBuffer(t testing.TB, name string, b *cellbuf.Buffer) {
+	t.Helper()
+	Assert(t, name, Encode(b))
+}
+
+// AssertANSI is Assert for the snapshot of a styled string at w×h.
+func AssertANSI(t testing.TB, name, s string, w, h int) {
+	t.Helper()
+	Assert(t, name, EncodeANSI(s, w, h))
+}
+
+// firstDiff describes the first line where want and got differ.
+func firstDiff(want, got string) string {
+	wl, gl := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < max(len(wl), len(gl)); i++ {
+		var w, g string
+		if i < len(wl) {
+			w = wl[i]
+		}
+		if i < len(gl) {
+			g = gl[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want %q\n  got  %q", i+1, w, g)
+		}
+	}
+	return "(no difference found)"
+}
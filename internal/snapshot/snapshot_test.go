@@ -0,0 +1,41 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/wesen/grail/pkg/cellbuf"
+)
+
+func TestEncode(t *testing.T) {
+	b := cellbuf.New(4, 2, 0)
+	b.SetString(0, 0, "a漢", 1)
+	b.SetRich(3, 1, 'z', 0, cellbuf.RichStyle{Fg: cellbuf.RGB(0xff, 0, 0), Attrs: cellbuf.Bold})
+	want := `-- text 4x2 --
+|a漢 |
+|   z|
+-- styles --
+|aaab|
+|bbbc|
+-- legend --
+a key=1
+b key=0
+c key=0 fg=#ff0000 bold
+`
+	if got := Encode(b); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEncodeANSIClips(t *testing.T) {
+	got := EncodeANSI("\x1b[1mhello\x1b[m\nworld", 3, 1)
+	want := `-- text 3x1 --
+|hel|
+-- styles --
+|aaa|
+-- legend --
+a key=0 bold
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
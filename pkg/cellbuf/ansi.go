@@ -0,0 +1,131 @@
+package cellbuf
+
+import (
+	"image/color"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// tabWidth is the distance between tab stops in SetANSI, counted from
+// the x the string is drawn at.
+const tabWidth = 8
+
+// SetANSI draws an ANSI-styled string, such as the output of a lipgloss
+// style, with its top-left at (x, y). Each line starts at x; "\r" returns
+// to x and tabs advance to the next multiple of 8 columns.
+//
+// Cells get the given StyleKey and a rich style built from the SGR
+// sequences in effect: colors (basic, 256-color and 24-bit) and bold,
+// italic, underline and reverse. A reset returns to the plain StyleKey
+// style. Attributes a RichStyle cannot hold, such as faint, and all other
+// escape sequences are ignored. Characters outside the buffer are
+// clipped.
+func (b *Buffer) SetANSI(x, y int, s string, style StyleKey) {
+	p := ansi.NewParser()
+	var rich RichStyle
+	var state byte
+	cx := x
+	for len(s) > 0 {
+		seq, width, n, newState := ansi.DecodeSequence(s, state, p)
+		state, s = newState, s[n:]
+		switch {
+		case width > 0:
+			cx += b.setCluster(cx, y, seq, style, rich)
+		case seq == "\n":
+			cx, y = x, y+1
+		case seq == "\r":
+			cx = x
+		case seq == "\t":
+			next := x + ((cx-x)/tabWidth+1)*tabWidth
+			for ; cx < next; cx++ {
+				b.put(cx, y, ' ', "", 1, style, rich)
+			}
+		case ansi.Cmd(p.Command()).Final() == 'm' && ansi.HasCsiPrefix(seq):
+			rich = applySGR(rich, p.Params())
+		}
+	}
+}
+
+// ParseANSI returns a transparent buffer holding an ANSI-styled string,
+// sized to fit it, for composing with Blit. Cells past the end of a
+// short line stay transparent; see SetANSI for how the string is read.
+func ParseANSI(s string, style StyleKey) *Buffer {
+	b := NewTransparent(lipgloss.Width(s), lipgloss.Height(s))
+	b.SetANSI(0, 0, s, style)
+	return b
+}
+
+// applySGR returns rs updated by the parameters of an SGR sequence.
+func applySGR(rs RichStyle, params ansi.Params) RichStyle {
+	if len(params) == 0 {
+		return RichStyle{}
+	}
+	for i := 0; i < len(params); i++ {
+		switch p := params[i].Param(0); {
+		case p == 0:
+			rs = RichStyle{}
+		case p == 1:
+			rs.Attrs |= Bold
+		case p == 3:
+			rs.Attrs |= Italic
+		case p == 4:
+			rs.Attrs |= Underline
+			if params[i].HasMore() && i+1 < len(params) {
+				i++ // 4:n selects an underline style; 4:0 is none
+				if params[i].Param(0) == 0 {
+					rs.Attrs &^= Underline
+				}
+			}
+		case p == 7:
+			rs.Attrs |= Reverse
+		case p == 22:
+			rs.Attrs &^= Bold
+		case p == 23:
+			rs.Attrs &^= Italic
+		case p == 24:
+			rs.Attrs &^= Underline
+		case p == 27:
+			rs.Attrs &^= Reverse
+		case p >= 30 && p <= 37:
+			rs.Fg = packColor(ansi.BasicColor(p - 30))
+		case p >= 90 && p <= 97:
+			rs.Fg = packColor(ansi.BasicColor(p - 90 + 8))
+		case p == 39:
+			rs.Fg = 0
+		case p >= 40 && p <= 47:
+			rs.Bg = packColor(ansi.BasicColor(p - 40))
+		case p >= 100 && p <= 107:
+			rs.Bg = packColor(ansi.BasicColor(p - 100 + 8))
+		case p == 49:
+			rs.Bg = 0
+		case p == 38, p == 48, p == 58:
+			var c color.Color
+			n := ansi.ReadStyleColor(params[i:], &c)
+			if n == 0 {
+				return rs // malformed; the rest cannot be trusted
+			}
+			switch p {
+			case 38:
+				rs.Fg = packColor(c)
+			case 48:
+				rs.Bg = packColor(c)
+			}
+			i += n - 1
+		}
+	}
+	return rs
+}
+
+// packColor converts a color to a Color. Nil and fully transparent
+// colors are unset.
+func packColor(c color.Color) Color {
+	if c == nil {
+		return 0
+	}
+	r, g, b, a := c.RGBA()
+	if a == 0 {
+		return 0
+	}
+	return RGB(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+}
@@ -0,0 +1,86 @@
+package cellbuf
+
+import (
+	"strings"
+	"testing"
+
+	"charm.land/lipgloss/v2"
+)
+
+func TestSetANSI(t *testing.T) {
+	b := New(8, 3, testBG)
+	b.SetANSI(1, 0, "\x1b[1;31mab\x1b[0mc\n\x1b[38;2;1;2;3;48;5;196mx\x1b[39my", testBlue)
+
+	want := []struct {
+		x, y int
+		ch   rune
+		rich RichStyle
+	}{
+		{1, 0, 'a', RichStyle{Fg: RGB(0x80, 0, 0), Attrs: Bold}},
+		{2, 0, 'b', RichStyle{Fg: RGB(0x80, 0, 0), Attrs: Bold}},
+		{3, 0, 'c', RichStyle{}},
+		{1, 1, 'x', RichStyle{Fg: RGB(1, 2, 3), Bg: RGB(0xff, 0, 0)}},
+		{2, 1, 'y', RichStyle{Bg: RGB(0xff, 0, 0)}},
+	}
+	for _, w := range want {
+		c := b.Cells[w.y][w.x]
+		if c.Ch != w.ch || c.Rich != w.rich || c.Style != testBlue {
+			t.Errorf("(%d,%d): got %q %v/%d, want %q %v/%d", w.x, w.y, c.Ch, c.Rich, c.Style, w.ch, w.rich, testBlue)
+		}
+	}
+	if b.Cells[0][0].Ch != ' ' || b.Cells[1][0].Ch != ' ' {
+		t.Error("lines should start at the given x")
+	}
+}
+
+func TestSetANSIAttributes(t *testing.T) {
+	b := New(6, 1, testBG)
+	b.SetANSI(0, 0, "\x1b[3;4;7mA\x1b[23mB\x1b[4:0mC\x1b[27mD\x1b[mE", testBG)
+	want := []Attrs{Italic | Underline | Reverse, Underline | Reverse, Reverse, 0, 0}
+	for x, a := range want {
+		if got := b.Cells[0][x].Rich.Attrs; got != a {
+			t.Errorf("cell %d: attrs %b, want %b", x, got, a)
+		}
+	}
+}
+
+func TestSetANSITabsAndWide(t *testing.T) {
+	b := New(13, 1, testBG)
+	b.SetANSI(2, 0, "a\t漢b", testBG)
+	if got := strings.Split(plain(b), "\n")[0]; got != "  a       漢b" {
+		t.Errorf("got %q", got)
+	}
+	if !b.Cells[0][10].Wide {
+		t.Error("wide character should take two cells")
+	}
+}
+
+func TestParseANSILipgloss(t *testing.T) {
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#00ff00")).
+		Foreground(lipgloss.Color("#ff00ff")).
+		Bold(true).
+		Render("hi")
+	b := ParseANSI(box, testBG)
+	if b.W != 4 || b.H != 3 {
+		t.Fatalf("expected 4x3, got %dx%d", b.W, b.H)
+	}
+	if b.Cells[0][0].Ch != '╭' || b.Cells[0][0].Rich.Fg != RGB(0, 0xff, 0) {
+		t.Errorf("corner: got %q %v", b.Cells[0][0].Ch, b.Cells[0][0].Rich)
+	}
+	if c := b.Cells[1][1]; c.Ch != 'h' || c.Rich != (RichStyle{Fg: RGB(0xff, 0, 0xff), Attrs: Bold}) {
+		t.Errorf("text: got %q %v", c.Ch, c.Rich)
+	}
+
+	// Composed onto another buffer, the box replaces what was under it.
+	dst := New(6, 3, testBG)
+	dst.Blit(1, 0, b)
+	var row strings.Builder
+	for _, c := range dst.Cells[1] {
+		row.WriteString(c.Text())
+	}
+	if row.String() != " │hi│ " {
+		t.Errorf("got %q", row.String())
+	}
+}
@@ -0,0 +1,29 @@
+package cellbuf
+
+// brailleBlank is the Braille pattern with no dots (U+2800). The low
+// eight bits of a pattern's code point are its dots.
+const brailleBlank rune = 0x2800
+
+// SetBraille adds dots to the Braille pattern in the cell at (x, y). If
+// the cell already holds a Braille pattern the dots are merged with its
+// own, so that lines drawn through the same cell combine; any other
+// character is replaced. The cell takes the given style.
+//
+// The bits of dots follow Unicode's numbering: 0x01, 0x02, 0x04 and 0x40
+// are the left column from top to bottom, 0x08, 0x10, 0x20 and 0x80 the
+// right column.
+func (b *Buffer) SetBraille(x, y int, dots uint8, style StyleKey) {
+	if !b.InBounds(x, y) {
+		return
+	}
+	ch := brailleBlank | rune(dots)
+	if old := b.Cells[y][x].Ch; IsBraille(old) {
+		ch |= old
+	}
+	b.put(x, y, ch, "", 1, style, RichStyle{})
+}
+
+// IsBraille reports whether ch is a Braille pattern.
+func IsBraille(ch rune) bool {
+	return ch&^0xff == brailleBlank
+}
@@ -0,0 +1,23 @@
+package cellbuf
+
+import "testing"
+
+func TestSetBrailleMerges(t *testing.T) {
+	b := New(3, 1, testBG)
+	b.SetBraille(0, 0, 0x01, testRed)
+	b.SetBraille(0, 0, 0x80, testBlue)
+	if got := b.Cells[0][0]; got.Ch != '⢁' || got.Style != testBlue {
+		t.Errorf("expected merged ⢁ in the last style, got %q/%d", got.Ch, got.Style)
+	}
+
+	b.Set(1, 0, '─', testRed)
+	b.SetBraille(1, 0, 0x02, testRed)
+	if got := b.Cells[0][1].Ch; got != '⠂' {
+		t.Errorf("dots should replace other characters, got %q", got)
+	}
+
+	b.SetBraille(5, 0, 0x01, testRed) // out of bounds: ignored
+	if !IsBraille('⠀') || IsBraille('x') || IsBraille('⣿'+1) {
+		t.Error("IsBraille misclassifies")
+	}
+}
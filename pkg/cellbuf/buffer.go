@@ -1,28 +1,71 @@
 // Package cellbuf provides a 2D character buffer with per-cell styling
 // and efficient Lipgloss-based rendering.
 //
-// Each cell holds a rune and a StyleKey (an int enum). At render time,
-// the caller provides a map[StyleKey]lipgloss.Style so the buffer is
-// decoupled from specific color schemes.
+// Each cell holds a grapheme cluster (usually a single rune) and a
+// StyleKey (an int enum). At render time, the caller provides a
+// map[StyleKey]lipgloss.Style so the buffer is decoupled from specific
+// color schemes. Cells may also carry a RichStyle with explicit colors
+// and attributes, for effects such as heat maps that would otherwise
+// need one key per color.
 //
-// Limitation: all runes are assumed to be single-width. CJK or other
-// double-width characters are not handled correctly.
+// Wide characters (CJK, most emoji) occupy two cells: the left cell holds
+// the character and is marked Wide, the right cell is a continuation cell
+// marked Cont. Overwriting either half of a wide character blanks the
+// other half, so the grid never holds half a character.
+//
+// Widgets can draw into a Sub view of a larger buffer, or into their own
+// buffer (often a NewTransparent one) that is later composed with Blit.
+// Content already rendered by lipgloss can be drawn in with SetANSI or
+// ParseANSI.
 package cellbuf
 
+import (
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
 // StyleKey identifies a visual style. The caller defines the mapping
 // from StyleKey to lipgloss.Style at render time.
 type StyleKey int
 
 // Cell is a single character in the buffer with an associated style.
 type Cell struct {
-	Ch    rune
+	Ch    rune // first rune of the cluster; ' ' in a continuation cell
 	Style StyleKey
+	Rich  RichStyle // optional override of Style; see RichStyle
+
+	// Cluster holds the full grapheme cluster when it is more than one
+	// rune (e.g. a base letter with combining marks, or an emoji
+	// sequence). It is empty for single-rune cells.
+	Cluster string
+
+	Wide bool // the character is two cells wide; the next cell is its continuation
+	Cont bool // right half of the wide character in the previous cell
+}
+
+// Text returns the cell's grapheme cluster, or "" for a continuation cell.
+func (c Cell) Text() string {
+	switch {
+	case c.Cont:
+		return ""
+	case c.Cluster != "":
+		return c.Cluster
+	}
+	return string(c.Ch)
 }
 
 // Buffer is a 2D grid of styled cells.
+//
+// The buffer tracks which cells changed since the last Render and caches
+// rendered lines, so a buffer kept between frames only re-renders rows
+// whose cells differ. Code that writes to Cells directly must call
+// MarkDirty.
 type Buffer struct {
 	W, H  int
 	Cells [][]Cell // [row][col]
+
+	cache renderCache
 }
 
 // New creates a Buffer of the given size, filled with spaces in the
@@ -50,19 +93,74 @@ func (b *Buffer) InBounds(x, y int) bool {
 	return x >= 0 && x < b.W && y >= 0 && y < b.H
 }
 
-// Set writes a single character at (x, y). Out-of-bounds writes are
-// silently ignored.
+// Set writes a single character at (x, y). A wide rune also claims the
+// cell to its right. Out-of-bounds writes are silently ignored.
 func (b *Buffer) Set(x, y int, ch rune, style StyleKey) {
-	if b.InBounds(x, y) {
-		b.Cells[y][x] = Cell{Ch: ch, Style: style}
+	b.put(x, y, ch, "", runeWidth(ch), style, RichStyle{})
+}
+
+// SetRich is like Set but also gives the cell a rich style.
+func (b *Buffer) SetRich(x, y int, ch rune, style StyleKey, rich RichStyle) {
+	b.put(x, y, ch, "", runeWidth(ch), style, rich)
+}
+
+// Restyle changes the rich style of the cell at (x, y) (and of the other
+// half of a wide character) without touching its text or StyleKey.
+func (b *Buffer) Restyle(x, y int, rich RichStyle) {
+	if !b.InBounds(x, y) {
+		return
+	}
+	row := b.Cells[y]
+	row[x].Rich = rich
+	b.mark(x-1, x+2, y)
+	switch {
+	case row[x].Cont && x > 0:
+		row[x-1].Rich = rich
+	case row[x].Wide && x+1 < b.W:
+		row[x+1].Rich = rich
+	}
+}
+
+// SetCluster writes one grapheme cluster at (x, y) and returns the
+// number of cells it occupies (1 or 2).
+func (b *Buffer) SetCluster(x, y int, cluster string, style StyleKey) int {
+	return b.setCluster(x, y, cluster, style, RichStyle{})
+}
+
+func (b *Buffer) setCluster(x, y int, cluster string, style StyleKey, rich RichStyle) int {
+	ch, size := utf8.DecodeRuneInString(cluster)
+	if size == len(cluster) {
+		w := runeWidth(ch)
+		b.put(x, y, ch, "", w, style, rich)
+		return w
 	}
+	w := min(max(uniseg.StringWidth(cluster), 1), 2)
+	b.put(x, y, ch, cluster, w, style, rich)
+	return w
 }
 
-// SetString writes a string starting at (x, y), advancing x for each
-// rune. Characters that fall outside the buffer are silently skipped.
+// SetString writes a string starting at (x, y), advancing x by the
+// display width of each grapheme cluster. Characters that fall outside
+// the buffer are silently skipped.
 func (b *Buffer) SetString(x, y int, s string, style StyleKey) {
-	for i, ch := range s {
-		b.Set(x+i, y, ch, style)
+	b.SetStringRich(x, y, s, style, RichStyle{})
+}
+
+// SetStringRich is like SetString but also gives each cell a rich style.
+func (b *Buffer) SetStringRich(x, y int, s string, style StyleKey, rich RichStyle) {
+	if simpleString(s) {
+		i := 0
+		for _, ch := range s {
+			b.put(x+i, y, ch, "", 1, style, rich)
+			i++
+		}
+		return
+	}
+	state := -1
+	for s != "" {
+		var cluster string
+		cluster, s, _, state = uniseg.FirstGraphemeClusterInString(s, state)
+		x += b.setCluster(x, y, cluster, style, rich)
 	}
 }
 
@@ -73,4 +171,60 @@ func (b *Buffer) Fill(style StyleKey) {
 			b.Cells[y][x] = Cell{Ch: ' ', Style: style}
 		}
 	}
+	b.markAll()
+}
+
+// put stores a character of the given width at (x, y), first blanking
+// any wide character it partially overwrites. A wide character that
+// does not fit before the right edge is replaced by a space.
+func (b *Buffer) put(x, y int, ch rune, cluster string, width int, style StyleKey, rich RichStyle) {
+	if !b.InBounds(x, y) {
+		return
+	}
+	b.mark(x-1, x+width+1, y) // covers halves blanked by unsplit
+	b.unsplit(x, y)
+	if width == 2 {
+		if x+1 >= b.W {
+			b.Cells[y][x] = Cell{Ch: ' ', Style: style, Rich: rich}
+			return
+		}
+		b.unsplit(x+1, y)
+		b.Cells[y][x] = Cell{Ch: ch, Style: style, Rich: rich, Cluster: cluster, Wide: true}
+		b.Cells[y][x+1] = Cell{Ch: ' ', Style: style, Rich: rich, Cont: true}
+		return
+	}
+	b.Cells[y][x] = Cell{Ch: ch, Style: style, Rich: rich, Cluster: cluster}
+}
+
+// unsplit blanks the other half of a wide character occupying (x, y),
+// keeping its style, so that (x, y) can be overwritten on its own.
+func (b *Buffer) unsplit(x, y int) {
+	row := b.Cells[y]
+	switch {
+	case row[x].Cont && x > 0:
+		row[x-1] = Cell{Ch: ' ', Style: row[x-1].Style, Rich: row[x-1].Rich}
+	case row[x].Wide && x+1 < b.W:
+		row[x+1] = Cell{Ch: ' ', Style: row[x+1].Style, Rich: row[x+1].Rich}
+	}
+}
+
+// runeWidth returns the display width of a single rune, clamped to 1 or
+// 2. Zero-width runes written on their own still occupy a cell.
+func runeWidth(ch rune) int {
+	if ch < 0x1100 { // no wide characters below Hangul Jamo
+		return 1
+	}
+	return min(max(uniseg.StringWidth(string(ch)), 1), 2)
+}
+
+// simpleString reports whether every rune in s is a single-width
+// character that cannot combine with its neighbours, so s can be
+// written one rune per cell without grapheme segmentation.
+func simpleString(s string) bool {
+	for _, ch := range s {
+		if ch >= 0x300 { // combining diacritics start at U+0300
+			return false
+		}
+	}
+	return true
 }
@@ -231,6 +231,7 @@ func BenchmarkRender200x50(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
+		buf.Invalidate() // measure a full render, not the line cache
 		_ = buf.Render(styles)
 	}
 }
@@ -255,6 +256,212 @@ func BenchmarkRenderRealistic(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
+		buf.Invalidate() // measure a full render, not the line cache
+		_ = buf.Render(styles)
+	}
+}
+
+// ── Wide characters and grapheme clusters ──
+
+func TestSetWideRune(t *testing.T) {
+	b := New(5, 1, testBG)
+	b.Set(1, 0, '漢', testRed)
+	if c := b.Cells[0][1]; c.Ch != '漢' || !c.Wide || c.Style != testRed {
+		t.Errorf("expected wide 漢/testRed at 1, got %+v", c)
+	}
+	if c := b.Cells[0][2]; !c.Cont || c.Style != testRed {
+		t.Errorf("expected continuation at 2, got %+v", c)
+	}
+}
+
+func TestSetStringWide(t *testing.T) {
+	b := New(8, 1, testBG)
+	b.SetString(0, 0, "日本x", testBlue)
+	if b.Cells[0][0].Ch != '日' || !b.Cells[0][1].Cont {
+		t.Error("expected 日 in cells 0-1")
+	}
+	if b.Cells[0][2].Ch != '本' || !b.Cells[0][3].Cont {
+		t.Error("expected 本 in cells 2-3")
+	}
+	if b.Cells[0][4].Ch != 'x' {
+		t.Errorf("expected x at 4 after two wide chars, got %q", b.Cells[0][4].Ch)
+	}
+}
+
+func TestSetStringClusters(t *testing.T) {
+	b := New(6, 1, testBG)
+	b.SetString(0, 0, "éa👍🏽b", testRed)
+	if c := b.Cells[0][0]; c.Cluster != "é" || c.Wide {
+		t.Errorf("expected e+combining acute in one narrow cell, got %+v", c)
+	}
+	if b.Cells[0][1].Ch != 'a' {
+		t.Errorf("expected a at 1, got %q", b.Cells[0][1].Ch)
+	}
+	if c := b.Cells[0][2]; c.Cluster != "👍🏽" || !c.Wide || !b.Cells[0][3].Cont {
+		t.Errorf("expected emoji with skin tone in cells 2-3, got %+v", c)
+	}
+	if b.Cells[0][4].Ch != 'b' {
+		t.Errorf("expected b at 4, got %q", b.Cells[0][4].Ch)
+	}
+}
+
+func TestOverwriteHalfOfWide(t *testing.T) {
+	// Overwriting the right half blanks the left half
+	b := New(4, 1, testBG)
+	b.Set(0, 0, '漢', testRed)
+	b.Set(1, 0, 'x', testBlue)
+	if c := b.Cells[0][0]; c.Ch != ' ' || c.Wide || c.Style != testRed {
+		t.Errorf("left half: expected plain space keeping style, got %+v", c)
+	}
+	if c := b.Cells[0][1]; c.Ch != 'x' || c.Cont {
+		t.Errorf("right half: expected x, got %+v", c)
+	}
+
+	// Overwriting the left half blanks the right half
+	b.Set(2, 0, '漢', testRed)
+	b.Set(2, 0, 'y', testBlue)
+	if c := b.Cells[0][3]; c.Ch != ' ' || c.Cont {
+		t.Errorf("orphaned continuation: expected plain space, got %+v", c)
+	}
+
+	// A wide char over the right half of another blanks that one's left half
+	b.Set(0, 0, '漢', testRed)
+	b.Set(1, 0, '字', testBlue)
+	if c := b.Cells[0][0]; c.Wide || c.Ch != ' ' {
+		t.Errorf("expected first wide char blanked, got %+v", c)
+	}
+	if c := b.Cells[0][1]; c.Ch != '字' || !c.Wide || !b.Cells[0][2].Cont {
+		t.Errorf("expected 字 in cells 1-2, got %+v", c)
+	}
+}
+
+func TestWideAtRightEdge(t *testing.T) {
+	b := New(3, 1, testBG)
+	b.Set(2, 0, '漢', testRed)
+	if c := b.Cells[0][2]; c.Ch != ' ' || c.Wide {
+		t.Errorf("wide char that does not fit should become a space, got %+v", c)
+	}
+}
+
+func TestRenderWide(t *testing.T) {
+	b := New(6, 2, testBG)
+	b.SetString(0, 0, "a漢b", testBG)
+	b.SetString(0, 1, "é👍🏽", testRed)
+	lines := strings.Split(b.Render(map[StyleKey]lipgloss.Style{}), "\n")
+	if lines[0] != "a漢b  " {
+		t.Errorf("line 0: expected %q, got %q", "a漢b  ", lines[0])
+	}
+	if lines[1] != "é👍🏽   " {
+		t.Errorf("line 1: expected %q, got %q", "é👍🏽   ", lines[1])
+	}
+	for i, l := range lines {
+		if w := lipgloss.Width(l); w != 6 {
+			t.Errorf("line %d: expected display width 6, got %d", i, w)
+		}
+	}
+}
+
+// ── Rich styles ──
+
+func TestColor(t *testing.T) {
+	var unset Color
+	if unset.IsSet() {
+		t.Error("zero Color should be unset")
+	}
+	c := RGB(0x12, 0x34, 0x56)
+	if !c.IsSet() || c.String() != "#123456" {
+		t.Errorf("expected set #123456, got %v", c)
+	}
+	if r, g, b, a := c.RGBA(); r != 0x1212 || g != 0x3434 || b != 0x5656 || a != 0xffff {
+		t.Errorf("RGBA: got %x %x %x %x", r, g, b, a)
+	}
+}
+
+func TestSetRich(t *testing.T) {
+	b := New(5, 1, testBG)
+	rs := RichStyle{Fg: RGB(255, 0, 0), Attrs: Bold}
+	b.SetRich(1, 0, 'X', testBlue, rs)
+	if c := b.Cells[0][1]; c.Ch != 'X' || c.Style != testBlue || c.Rich != rs {
+		t.Errorf("expected X/testBlue with rich style, got %+v", c)
+	}
+	b.Set(1, 0, 'Y', testBlue)
+	if !b.Cells[0][1].Rich.IsZero() {
+		t.Error("Set should clear the rich style")
+	}
+}
+
+func TestRestyle(t *testing.T) {
+	b := New(5, 1, testBG)
+	b.SetString(0, 0, "a漢", testRed)
+	rs := RichStyle{Bg: RGB(0, 0, 255)}
+	b.Restyle(2, 0, rs) // continuation half
+	if b.Cells[0][1].Rich != rs || b.Cells[0][2].Rich != rs {
+		t.Error("Restyle should cover both halves of a wide char")
+	}
+	if b.Cells[0][1].Ch != '漢' || b.Cells[0][1].Style != testRed {
+		t.Error("Restyle should not change text or key")
+	}
+}
+
+func TestRenderRich(t *testing.T) {
+	styles := testStyles()
+	b := New(6, 1, testBG)
+	for x := 0; x < 3; x++ {
+		b.SetRich(x, 0, 'x', testBG, RichStyle{Fg: RGB(10, 200, 30)})
+	}
+	out := b.Render(styles)
+	if !strings.Contains(out, "xxx") {
+		t.Errorf("identical rich cells should render as one run: %q", out)
+	}
+	if !strings.Contains(out, "10;200;30") {
+		t.Errorf("expected the rich foreground in the output: %q", out)
+	}
+	if strings.Count(out, "10;200;30") != 1 {
+		t.Errorf("expected one escape for the rich run, got %q", out)
+	}
+}
+
+// heatMap fills a buffer with a per-column color gradient, the case rich
+// styles exist for: 150 distinct colors per row.
+func heatMap(w, h int) *Buffer {
+	buf := New(w, h, testBG)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			buf.SetRich(x, y, '█', testBG, RichStyle{Fg: RGB(uint8(x*255/w), 64, uint8(255-x*255/w))})
+		}
+	}
+	return buf
+}
+
+func BenchmarkRenderHeatMap(b *testing.B) {
+	styles := testStyles()
+	buf := heatMap(150, 40)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Invalidate() // measure a full render, not the line cache
+		_ = buf.Render(styles)
+	}
+}
+
+// BenchmarkRenderRealisticRich is BenchmarkRenderRealistic with the edge
+// cells colored through RichStyle instead of a key, to compare the two
+// paths on the same run structure.
+func BenchmarkRenderRealisticRich(b *testing.B) {
+	styles := testStyles()
+	edge := RichStyle{Fg: RGB(0, 0, 255)}
+	buf := New(150, 40, testBG)
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 150; x++ {
+			if x%5 == 0 && y%3 == 0 {
+				buf.Set(x, y, '·', testRed)
+			}
+		}
+		buf.SetRich(y, y%40, '/', testBG, edge)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Invalidate() // measure a full render, not the line cache
 		_ = buf.Render(styles)
 	}
 }
@@ -0,0 +1,118 @@
+package cellbuf
+
+import (
+	"image"
+	"reflect"
+	"slices"
+)
+
+// span is a half-open range of dirty columns [x0, x1) in one row. It is
+// empty when the row is clean.
+type span struct{ x0, x1 int }
+
+func (s span) empty() bool { return s.x1 <= s.x0 }
+
+// renderCache holds the output of the last Render so that rows that did
+// not change can be reused.
+type renderCache struct {
+	dirty  []span      // per row, changes since the last Render
+	lines  []string    // rendered rows
+	snap   [][]Cell    // cells of each row as of the last Render
+	styles uintptr     // identity of the styles map the lines were rendered with
+	parent *Buffer     // for views: the buffer whose cells they share
+	origin image.Point // for views: the view's origin in the parent
+}
+
+// MarkDirty records that the cells in r changed. The drawing methods do
+// this themselves; call it after writing to Cells directly, or the next
+// Render may reuse stale lines.
+func (b *Buffer) MarkDirty(r image.Rectangle) {
+	r = r.Intersect(b.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		b.mark(r.Min.X, r.Max.X, y)
+	}
+}
+
+// Invalidate drops the render cache, so the next Render rebuilds every
+// line. Call it after changing the contents of the styles map passed to
+// Render; passing a different map is detected automatically.
+func (b *Buffer) Invalidate() {
+	for y := range b.cache.snap {
+		b.cache.snap[y] = b.cache.snap[y][:0] // keep capacity for reuse
+	}
+}
+
+// DirtySpan returns the columns [x0, x1) of row y that changed since the
+// last Render. ok is false if the row is clean.
+func (b *Buffer) DirtySpan(y int) (x0, x1 int, ok bool) {
+	if y < 0 || y >= len(b.cache.dirty) || b.cache.dirty[y].empty() {
+		return 0, 0, false
+	}
+	s := b.cache.dirty[y]
+	return s.x0, s.x1, true
+}
+
+// mark extends row y's dirty span to cover [x0, x1), clipped to the
+// buffer, and marks the same cells in the parent of a view.
+func (b *Buffer) mark(x0, x1, y int) {
+	x0, x1 = max(x0, 0), min(x1, b.W)
+	if y < 0 || y >= b.H || x1 <= x0 {
+		return
+	}
+	if len(b.cache.dirty) != b.H {
+		b.cache.dirty = make([]span, b.H)
+	}
+	s := &b.cache.dirty[y]
+	if s.empty() {
+		*s = span{x0, x1}
+	} else {
+		*s = span{min(s.x0, x0), max(s.x1, x1)}
+	}
+	if p := b.cache.parent; p != nil {
+		o := b.cache.origin
+		p.mark(x0+o.X, x1+o.X, y+o.Y)
+	}
+}
+
+// markAll marks every cell dirty.
+func (b *Buffer) markAll() {
+	for y := 0; y < b.H; y++ {
+		b.mark(0, b.W, y)
+	}
+}
+
+// cachedLine returns the cached rendering of row y if it is still valid:
+// either the row is clean, or it was rewritten with the same cells (as
+// when a frame is cleared and redrawn identically).
+func (b *Buffer) cachedLine(y int) (string, bool) {
+	c := &b.cache
+	if len(c.snap[y]) == 0 {
+		return "", false
+	}
+	if y >= len(c.dirty) || c.dirty[y].empty() || slices.Equal(c.snap[y], b.Cells[y]) {
+		return c.lines[y], true
+	}
+	return "", false
+}
+
+// prepareCache resets the cache if the buffer was resized or a different
+// styles map is used.
+func (b *Buffer) prepareCache(styles any) {
+	c := &b.cache
+	id := reflect.ValueOf(styles).Pointer()
+	switch {
+	case len(c.lines) != b.H:
+		c.lines = make([]string, b.H)
+		c.snap = make([][]Cell, b.H)
+	case c.styles != id:
+		b.Invalidate()
+	}
+	c.styles = id
+}
+
+// storeLine caches the rendering of row y.
+func (b *Buffer) storeLine(y int, line string) {
+	c := &b.cache
+	c.lines[y] = line
+	c.snap[y] = append(c.snap[y][:0], b.Cells[y]...)
+}
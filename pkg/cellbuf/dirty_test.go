@@ -0,0 +1,108 @@
+package cellbuf
+
+import (
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestDirtySpan(t *testing.T) {
+	b := New(10, 3, testBG)
+	b.Render(testStyles())
+	if _, _, ok := b.DirtySpan(1); ok {
+		t.Fatal("rows should be clean after Render")
+	}
+	b.Set(3, 1, 'x', testRed)
+	b.Set(6, 1, 'y', testRed)
+	x0, x1, ok := b.DirtySpan(1)
+	if !ok || x0 > 3 || x1 < 7 {
+		t.Errorf("expected dirty span covering 3..6, got [%d,%d) ok=%v", x0, x1, ok)
+	}
+	if _, _, ok := b.DirtySpan(0); ok {
+		t.Error("untouched row should stay clean")
+	}
+}
+
+func TestRenderReusesCleanRows(t *testing.T) {
+	styles := testStyles()
+	b := New(10, 2, testBG)
+	b.SetString(0, 0, "hello", testRed)
+	first := b.Render(styles)
+
+	// Write behind the tracker's back: a clean row is served from cache
+	b.Cells[0][0].Ch = 'J'
+	if got := b.Render(styles); got != first {
+		t.Error("clean row should come from the cache")
+	}
+	b.MarkDirty(image.Rect(0, 0, 1, 1))
+	if got := b.Render(styles); !strings.Contains(got, "Jello") {
+		t.Errorf("marked row should be re-rendered, got %q", got)
+	}
+}
+
+func TestRenderRedrawIdentical(t *testing.T) {
+	styles := testStyles()
+	b := New(10, 2, testBG)
+	b.SetString(0, 0, "abc", testRed)
+	first := b.Render(styles)
+	b.Fill(testBG)
+	b.SetString(0, 0, "abc", testRed)
+	if got := b.Render(styles); got != first {
+		t.Errorf("identical redraw should render the same output")
+	}
+	b.Fill(testBG)
+	b.SetString(0, 0, "abd", testRed)
+	if got := b.Render(styles); !strings.Contains(got, "abd") {
+		t.Errorf("changed redraw should be re-rendered, got %q", got)
+	}
+}
+
+func TestRenderStylesChange(t *testing.T) {
+	b := New(3, 1, testRed)
+	a := b.Render(testStyles())
+	other := testStyles()
+	other[testRed] = other[testBlue]
+	if got := b.Render(other); got == a {
+		t.Error("a different styles map should invalidate the cache")
+	}
+}
+
+func TestSubMarksParent(t *testing.T) {
+	b := New(10, 3, testBG)
+	b.Render(testStyles())
+	b.Sub(image.Rect(4, 1, 8, 3)).Set(1, 1, 'x', testRed)
+	x0, x1, ok := b.DirtySpan(2)
+	if !ok || x0 > 5 || x1 < 6 {
+		t.Errorf("write through a view should mark the parent, got [%d,%d) ok=%v", x0, x1, ok)
+	}
+}
+
+// BenchmarkRenderCached re-renders an unchanged buffer, as happens when
+// only the mouse moves.
+func BenchmarkRenderCached(b *testing.B) {
+	styles := testStyles()
+	buf := heatMap(150, 40)
+	buf.Render(styles)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = buf.Render(styles)
+	}
+}
+
+// BenchmarkRenderRedrawOneRow clears and redraws the whole buffer each
+// frame with one row actually changing, like the editor's edge canvas.
+func BenchmarkRenderRedrawOneRow(b *testing.B) {
+	styles := testStyles()
+	buf := New(150, 40, testBG)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Fill(testBG)
+		for y := 0; y < 40; y++ {
+			for x := 0; x < 150; x += 5 {
+				buf.Set(x, y, '·', testRed)
+			}
+		}
+		buf.Set(i%150, 20, '/', testBlue)
+		_ = buf.Render(styles)
+	}
+}
@@ -0,0 +1,148 @@
+package cellbuf
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+)
+
+// WriteText writes the buffer's characters without any styling, one
+// line per row, with trailing whitespace trimmed from each line.
+func (b *Buffer) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var line strings.Builder
+	for _, row := range b.Cells {
+		line.Reset()
+		for _, c := range row {
+			if c.Ch == Transparent && !c.Cont {
+				line.WriteByte(' ')
+				continue
+			}
+			line.WriteString(c.Text())
+		}
+		bw.WriteString(strings.TrimRight(line.String(), " \t"))
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// WriteANSI writes the buffer as it would appear in a terminal: the
+// output of Render followed by a final newline.
+func (b *Buffer) WriteANSI(w io.Writer, styles map[StyleKey]lipgloss.Style) error {
+	if b.W == 0 || b.H == 0 {
+		return nil
+	}
+	_, err := io.WriteString(w, b.Render(styles)+"\n")
+	return err
+}
+
+// WriteHTML writes the buffer as a standalone HTML page: a single <pre>
+// block in which each run of identically styled cells is a <span> with
+// inline colors and attributes taken from styles and the cells' rich
+// styles. The page background is that of the top-left cell.
+func (b *Buffer) WriteHTML(w io.Writer, styles map[StyleKey]lipgloss.Style, title string) error {
+	bw := bufio.NewWriter(w)
+	pageBG := "#000000"
+	if b.W > 0 && b.H > 0 {
+		if _, bg, _ := resolveCSS(styles, runKey{b.Cells[0][0].Style, b.Cells[0][0].Rich}); bg != "" {
+			pageBG = bg
+		}
+	}
+	fmt.Fprintf(bw, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+</head>
+<body style="margin:0;background:%s">
+<pre style="margin:0;padding:1em;font-family:'DejaVu Sans Mono',Menlo,Consolas,monospace;line-height:1.2;color:#cccccc">
+`, html.EscapeString(title), pageBG)
+
+	css := make(map[runKey]string)
+	var text strings.Builder
+	for _, row := range b.Cells {
+		for x := 0; x < len(row); {
+			key := runKey{row[x].Style, row[x].Rich}
+			text.Reset()
+			for ; x < len(row) && (runKey{row[x].Style, row[x].Rich}) == key; x++ {
+				switch c := row[x]; {
+				case c.Cont:
+				case c.Ch == Transparent:
+					text.WriteByte(' ')
+				default:
+					text.WriteString(c.Text())
+				}
+			}
+			style, ok := css[key]
+			if !ok {
+				style = spanCSS(styles, key)
+				css[key] = style
+			}
+			if style == "" {
+				bw.WriteString(html.EscapeString(text.String()))
+			} else {
+				fmt.Fprintf(bw, `<span style="%s">%s</span>`, style, html.EscapeString(text.String()))
+			}
+		}
+		bw.WriteByte('\n')
+	}
+	bw.WriteString("</pre>\n</body>\n</html>\n")
+	return bw.Flush()
+}
+
+// spanCSS returns the inline CSS for a run's resolved style.
+func spanCSS(styles map[StyleKey]lipgloss.Style, key runKey) string {
+	fg, bg, decl := resolveCSS(styles, key)
+	var parts []string
+	if fg != "" {
+		parts = append(parts, "color:"+fg)
+	}
+	if bg != "" {
+		parts = append(parts, "background:"+bg)
+	}
+	return strings.Join(append(parts, decl...), ";")
+}
+
+// resolveCSS resolves a run's colors (as "#rrggbb", or "" when unset)
+// and its other CSS declarations, applying the rich style over the keyed
+// one and swapping the colors for reverse video.
+func resolveCSS(styles map[StyleKey]lipgloss.Style, key runKey) (fg, bg string, decl []string) {
+	s := key.rich.apply(styles[key.key])
+	fg, bg = cssColor(s.GetForeground()), cssColor(s.GetBackground())
+	if s.GetReverse() {
+		fg, bg = bg, fg
+		if fg == "" {
+			fg = "#000000"
+		}
+		if bg == "" {
+			bg = "#cccccc"
+		}
+	}
+	if s.GetBold() {
+		decl = append(decl, "font-weight:bold")
+	}
+	if s.GetItalic() {
+		decl = append(decl, "font-style:italic")
+	}
+	if s.GetUnderline() {
+		decl = append(decl, "text-decoration:underline")
+	}
+	return fg, bg, decl
+}
+
+// cssColor formats c as "#rrggbb", or "" for no color.
+func cssColor(c color.Color) string {
+	if c == nil {
+		return ""
+	}
+	if _, ok := c.(lipgloss.NoColor); ok {
+		return ""
+	}
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
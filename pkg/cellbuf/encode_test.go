@@ -0,0 +1,62 @@
+package cellbuf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"charm.land/lipgloss/v2"
+)
+
+func TestWriteText(t *testing.T) {
+	b := New(8, 3, testBG)
+	b.SetString(1, 0, "ab", testRed)
+	b.SetString(0, 2, "漢x", testBlue)
+	var out bytes.Buffer
+	if err := b.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := " ab\n\n漢x\n"
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
+
+func TestWriteANSI(t *testing.T) {
+	styles := testStyles()
+	b := New(4, 2, testBG)
+	b.SetString(0, 0, "hi", testRed)
+	var out bytes.Buffer
+	if err := b.WriteANSI(&out, styles); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != b.Render(styles)+"\n" {
+		t.Errorf("WriteANSI should match Render plus a newline, got %q", out.String())
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	styles := map[StyleKey]lipgloss.Style{
+		testBG:  lipgloss.NewStyle().Background(lipgloss.Color("#101010")),
+		testRed: lipgloss.NewStyle().Foreground(lipgloss.Color("#ff0000")).Bold(true),
+	}
+	b := New(6, 1, testBG)
+	b.SetString(0, 0, "<a>", testRed)
+	b.SetRich(4, 0, '&', testBG, RichStyle{Fg: RGB(0, 0x80, 0xff), Attrs: Underline})
+
+	var out bytes.Buffer
+	if err := b.WriteHTML(&out, styles, "T & C"); err != nil {
+		t.Fatal(err)
+	}
+	s := out.String()
+	for _, want := range []string{
+		"<title>T &amp; C</title>",
+		"background:#101010",
+		`<span style="color:#ff0000;font-weight:bold">&lt;a&gt;</span>`,
+		`<span style="color:#0080ff;background:#101010;text-decoration:underline">&amp;</span>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("HTML output missing %q:\n%s", want, s)
+		}
+	}
+}
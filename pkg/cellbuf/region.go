@@ -0,0 +1,129 @@
+package cellbuf
+
+import "image"
+
+// Transparent is the Ch of a transparent cell. Blit leaves the
+// destination unchanged under transparent cells, and Render shows them
+// as spaces.
+const Transparent rune = 0
+
+// NewTransparent creates a Buffer of the given size in which every cell
+// is transparent, for drawing overlays to Blit onto another buffer.
+func NewTransparent(w, h int) *Buffer {
+	b := New(w, h, 0)
+	for _, row := range b.Cells {
+		for x := range row {
+			row[x].Ch = Transparent
+		}
+	}
+	return b
+}
+
+// Bounds returns the buffer's rectangle in its own coordinates.
+func (b *Buffer) Bounds() image.Rectangle {
+	return image.Rect(0, 0, b.W, b.H)
+}
+
+// Sub returns a view of the part of b inside r. The view has its own
+// origin at r.Min and shares cells with b, so drawing into it draws
+// into b, clipped to r. If r extends past b it is clipped first, and the
+// view's origin is the top-left of the clipped rectangle.
+//
+// A wide character straddling the edge of the view is not blanked when
+// only the half inside the view is overwritten. Changes made through the
+// view are marked dirty in b, but a view's own render cache does not see
+// changes made through b.
+func (b *Buffer) Sub(r image.Rectangle) *Buffer {
+	r = r.Intersect(b.Bounds())
+	sub := &Buffer{W: r.Dx(), H: r.Dy(), Cells: make([][]Cell, r.Dy())}
+	for y := range sub.Cells {
+		sub.Cells[y] = b.Cells[r.Min.Y+y][r.Min.X:r.Max.X:r.Max.X]
+	}
+	sub.cache.parent, sub.cache.origin = b, r.Min
+	return sub
+}
+
+// Blit draws src onto b with src's top-left at (x, y). Transparent
+// cells in src are skipped; everything outside b is clipped.
+func (b *Buffer) Blit(x, y int, src *Buffer) {
+	for sy, row := range src.Cells {
+		for sx, c := range row {
+			switch {
+			case c.Cont:
+				// drawn together with its wide character
+			case c.Ch == Transparent:
+			case c.Wide:
+				b.put(x+sx, y+sy, c.Ch, c.Cluster, 2, c.Style, c.Rich)
+			default:
+				b.put(x+sx, y+sy, c.Ch, c.Cluster, 1, c.Style, c.Rich)
+			}
+		}
+	}
+}
+
+// Resize changes the buffer's size, keeping the content at the top-left
+// and filling new cells with spaces in the given style. A sub-buffer
+// that is resized gets its own cells and no longer draws into its
+// parent.
+func (b *Buffer) Resize(w, h int, style StyleKey) {
+	nb := New(w, h, style)
+	for y := 0; y < min(b.H, nb.H); y++ {
+		copy(nb.Cells[y], b.Cells[y][:min(b.W, nb.W)])
+		nb.fixEdges(y)
+	}
+	*b = *nb
+}
+
+// Scroll moves the content by (dx, dy) cells — negative dy scrolls up,
+// as when appending lines to a console — and fills the vacated cells with
+// spaces in the given style. Content moved past the edges is discarded.
+func (b *Buffer) Scroll(dx, dy int, style StyleKey) {
+	blank := Cell{Ch: ' ', Style: style}
+	b.markAll()
+	// Walk rows away from the direction of travel so that each source
+	// row is read before it is overwritten. Cells are copied rather than
+	// rows swapped, so sub-buffers keep sharing their parent's rows.
+	for i := 0; i < b.H; i++ {
+		y := i
+		if dy > 0 {
+			y = b.H - 1 - i
+		}
+		dst := b.Cells[y]
+		srcY := y - dy
+		if srcY < 0 || srcY >= b.H {
+			for x := range dst {
+				dst[x] = blank
+			}
+			continue
+		}
+		src := b.Cells[srcY]
+		switch {
+		case dx >= 0:
+			copy(dst[min(dx, b.W):], src)
+			for x := 0; x < min(dx, b.W); x++ {
+				dst[x] = blank
+			}
+		default:
+			n := copy(dst, src[min(-dx, b.W):])
+			for x := n; x < b.W; x++ {
+				dst[x] = blank
+			}
+		}
+		b.fixEdges(y)
+	}
+}
+
+// fixEdges blanks the halves of wide characters cut off at the left and
+// right edges of row y.
+func (b *Buffer) fixEdges(y int) {
+	row := b.Cells[y]
+	if b.W == 0 {
+		return
+	}
+	if row[0].Cont {
+		row[0] = Cell{Ch: ' ', Style: row[0].Style, Rich: row[0].Rich}
+	}
+	if last := &row[b.W-1]; last.Wide {
+		*last = Cell{Ch: ' ', Style: last.Style, Rich: last.Rich}
+	}
+}
@@ -0,0 +1,130 @@
+package cellbuf
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"charm.land/lipgloss/v2"
+)
+
+// plain renders a buffer without styles.
+func plain(b *Buffer) string {
+	return b.Render(map[StyleKey]lipgloss.Style{})
+}
+
+func TestSubSharesCells(t *testing.T) {
+	b := New(10, 4, testBG)
+	sub := b.Sub(image.Rect(2, 1, 6, 3))
+	if sub.W != 4 || sub.H != 2 {
+		t.Fatalf("expected 4x2 view, got %dx%d", sub.W, sub.H)
+	}
+	sub.Set(0, 0, 'A', testRed)
+	sub.SetString(2, 1, "xyz", testBlue) // "z" falls outside the view
+	if b.Cells[1][2].Ch != 'A' {
+		t.Error("drawing into the view should draw into the parent")
+	}
+	if b.Cells[2][4].Ch != 'x' || b.Cells[2][5].Ch != 'y' {
+		t.Error("expected xy at (4,2)-(5,2) in the parent")
+	}
+	if b.Cells[2][6].Ch != ' ' {
+		t.Error("view should clip writes at its right edge")
+	}
+}
+
+func TestSubClipsToParent(t *testing.T) {
+	b := New(10, 4, testBG)
+	sub := b.Sub(image.Rect(8, -2, 20, 2))
+	if sub.W != 2 || sub.H != 2 {
+		t.Fatalf("expected clipped 2x2 view, got %dx%d", sub.W, sub.H)
+	}
+	sub.Set(0, 0, 'A', testRed)
+	if b.Cells[0][8].Ch != 'A' {
+		t.Error("clipped view should have its origin at the clipped corner")
+	}
+}
+
+func TestBlitTransparent(t *testing.T) {
+	b := New(6, 2, testBG)
+	b.SetString(0, 0, "abcdef", testBG)
+	over := NewTransparent(3, 1)
+	over.Set(0, 0, 'X', testRed)
+	over.Set(2, 0, 'Z', testRed)
+	b.Blit(1, 0, over)
+	if got := strings.Split(plain(b), "\n")[0]; got != "aXcZef" {
+		t.Errorf("expected aXcZef, got %q", got)
+	}
+	if b.Cells[0][1].Style != testRed || b.Cells[0][2].Style != testBG {
+		t.Error("only opaque cells should change style")
+	}
+}
+
+func TestBlitWideAndClip(t *testing.T) {
+	b := New(4, 1, testBG)
+	src := New(3, 2, testBlue)
+	src.SetString(0, 0, "漢a", testBlue)
+	b.Blit(2, 0, src) // 漢 fits at 2-3, "a" and row 1 are clipped
+	if c := b.Cells[0][2]; c.Ch != '漢' || !c.Wide || !b.Cells[0][3].Cont {
+		t.Errorf("expected wide char blitted at 2-3, got %+v", c)
+	}
+	b.Blit(-1, 0, src) // only the continuation half would land inside
+	if b.Cells[0][0].Cont {
+		t.Error("blitting must not leave an orphaned continuation cell")
+	}
+}
+
+func TestRenderTransparent(t *testing.T) {
+	b := NewTransparent(3, 1)
+	if got := plain(b); got != "   " {
+		t.Errorf("transparent cells should render as spaces, got %q", got)
+	}
+}
+
+func TestResize(t *testing.T) {
+	b := New(4, 2, testBG)
+	b.SetString(0, 0, "ab漢", testRed)
+	b.Resize(3, 3, testBlue)
+	if b.W != 3 || b.H != 3 || len(b.Cells) != 3 || len(b.Cells[0]) != 3 {
+		t.Fatalf("expected 3x3, got %dx%d", b.W, b.H)
+	}
+	if b.Cells[0][0].Ch != 'a' || b.Cells[0][1].Ch != 'b' {
+		t.Error("content should be kept at the top-left")
+	}
+	if b.Cells[0][2].Wide {
+		t.Error("wide char cut by the new edge should be blanked")
+	}
+	if c := b.Cells[2][0]; c.Ch != ' ' || c.Style != testBlue {
+		t.Errorf("new cells should be filled with the given style, got %+v", c)
+	}
+}
+
+func TestScroll(t *testing.T) {
+	b := New(3, 3, testBG)
+	b.SetString(0, 0, "abc", testBG)
+	b.SetString(0, 1, "def", testBG)
+	b.SetString(0, 2, "ghi", testBG)
+
+	b.Scroll(0, -1, testBG)
+	if got := plain(b); got != "def\nghi\n   " {
+		t.Errorf("scroll up: got %q", got)
+	}
+	b.Scroll(1, 1, testBG)
+	if got := plain(b); got != "   \n de\n gh" {
+		t.Errorf("scroll down-right: got %q", got)
+	}
+	b.Scroll(-2, 0, testBG)
+	if got := plain(b); got != "   \ne  \nh  " {
+		t.Errorf("scroll left: got %q", got)
+	}
+}
+
+func TestScrollSub(t *testing.T) {
+	b := New(5, 3, testBG)
+	b.SetString(0, 0, "11111", testBG)
+	b.SetString(0, 1, "22222", testBG)
+	b.SetString(0, 2, "33333", testBG)
+	b.Sub(image.Rect(1, 0, 4, 3)).Scroll(0, -1, testBG)
+	if got := plain(b); got != "12221\n23332\n3   3" {
+		t.Errorf("scrolling a view should only move its cells, got %q", got)
+	}
+}
@@ -2,6 +2,7 @@ package cellbuf
 
 import (
 	"strings"
+	"unicode/utf8"
 
 	"charm.land/lipgloss/v2"
 )
@@ -9,9 +10,18 @@ import (
 // Render converts the buffer into a styled string. The caller provides
 // a mapping from StyleKey to lipgloss.Style.
 //
-// Consecutive cells with the same StyleKey are merged into runs and
-// rendered with a single Style.Render() call per run. This is
+// Consecutive cells with the same StyleKey and RichStyle are merged into
+// runs and rendered with a single Style.Render() call per run. This is
 // significantly faster than per-cell rendering (see GRAIL-001 perf doc).
+// Styles for rich cells are built once per distinct (key, rich) pair and
+// cached for the rest of the call.
+//
+// Wide characters are written once; their continuation cells emit
+// nothing, so each line's display width equals W. Transparent cells
+// render as spaces.
+//
+// Rows whose cells are unchanged since the previous call are taken from
+// a cache instead of being rendered again (see MarkDirty).
 //
 // Rows are joined with "\n". An empty buffer (W==0 or H==0) returns "".
 func (b *Buffer) Render(styles map[StyleKey]lipgloss.Style) string {
@@ -19,48 +29,93 @@ func (b *Buffer) Render(styles map[StyleKey]lipgloss.Style) string {
 		return ""
 	}
 
+	b.prepareCache(styles)
 	lines := make([]string, b.H)
-	// Reusable rune buffer — avoids allocation per run
-	chunk := make([]rune, b.W)
-
+	r := lineRenderer{styles: styles, chunk: make([]byte, 0, b.W)}
 	for y := 0; y < b.H; y++ {
-		var sb strings.Builder
-		// Pre-size: each cell is ~1 byte content + ~10 bytes ANSI overhead
-		// amortized across runs. 2× width is a reasonable estimate.
-		sb.Grow(b.W * 2)
-		row := b.Cells[y]
+		if line, ok := b.cachedLine(y); ok {
+			lines[y] = line
+			continue
+		}
+		lines[y] = r.render(b.Cells[y])
+		b.storeLine(y, lines[y])
+	}
+	clear(b.cache.dirty)
 
-		runStart := 0
-		runStyle := row[0].Style
+	return strings.Join(lines, "\n")
+}
 
-		for x := 1; x <= b.W; x++ {
-			// Use sentinel style at end to flush last run
-			var curStyle StyleKey
-			if x < b.W {
-				curStyle = row[x].Style
-			} else {
-				curStyle = StyleKey(-1)
-			}
+// runKey is the resolved style of a run of cells.
+type runKey struct {
+	key  StyleKey
+	rich RichStyle
+}
+
+// lineRenderer renders rows of cells, reusing its scratch buffer and the
+// styles built for rich cells.
+type lineRenderer struct {
+	styles     map[StyleKey]lipgloss.Style
+	chunk      []byte
+	richStyles map[runKey]lipgloss.Style // built lazily
+}
+
+// render returns one row as a styled string.
+func (r *lineRenderer) render(row []Cell) string {
+	var sb strings.Builder
+	// Pre-size: each cell is ~1 byte content + ~10 bytes ANSI overhead
+	// amortized across runs. 2× width is a reasonable estimate.
+	sb.Grow(len(row) * 2)
+
+	runStart := 0
+	runStyle := runKey{row[0].Style, row[0].Rich}
 
-			if curStyle != runStyle {
-				// Flush the accumulated run into the reusable chunk
-				n := x - runStart
-				for i := 0; i < n; i++ {
-					chunk[i] = row[runStart+i].Ch
+	for x := 1; x <= len(row); x++ {
+		// Use sentinel style at end to flush last run
+		var curStyle runKey
+		if x < len(row) {
+			curStyle = runKey{row[x].Style, row[x].Rich}
+		} else {
+			curStyle = runKey{key: StyleKey(-1)}
+		}
+
+		if curStyle != runStyle {
+			// Flush the accumulated run into the reusable chunk.
+			// Continuation cells are covered by their wide
+			// character and contribute no text.
+			chunk := r.chunk[:0]
+			for _, c := range row[runStart:x] {
+				switch {
+				case c.Cont:
+				case c.Cluster != "":
+					chunk = append(chunk, c.Cluster...)
+				case c.Ch == Transparent:
+					chunk = append(chunk, ' ')
+				default:
+					chunk = utf8.AppendRune(chunk, c.Ch)
 				}
-				s := string(chunk[:n])
-				if style, ok := styles[runStyle]; ok {
+			}
+			r.chunk = chunk
+			s := string(chunk)
+			if runStyle.rich.IsZero() {
+				if style, ok := r.styles[runStyle.key]; ok {
 					sb.WriteString(style.Render(s))
 				} else {
 					sb.WriteString(s)
 				}
-				runStart = x
-				runStyle = curStyle
+			} else {
+				style, ok := r.richStyles[runStyle]
+				if !ok {
+					if r.richStyles == nil {
+						r.richStyles = make(map[runKey]lipgloss.Style)
+					}
+					style = runStyle.rich.apply(r.styles[runStyle.key])
+					r.richStyles[runStyle] = style
+				}
+				sb.WriteString(style.Render(s))
 			}
+			runStart = x
+			runStyle = curStyle
 		}
-
-		lines[y] = sb.String()
 	}
-
-	return strings.Join(lines, "\n")
+	return sb.String()
 }
@@ -0,0 +1,91 @@
+package cellbuf
+
+import (
+	"fmt"
+
+	"charm.land/lipgloss/v2"
+)
+
+// Color is a packed 24-bit RGB color. The zero value means "not set":
+// the cell keeps the color of its StyleKey style.
+type Color uint32
+
+const colorSet Color = 1 << 24
+
+// RGB returns the color with the given components.
+func RGB(r, g, b uint8) Color {
+	return colorSet | Color(r)<<16 | Color(g)<<8 | Color(b)
+}
+
+// IsSet reports whether c is a color rather than the zero value.
+func (c Color) IsSet() bool {
+	return c&colorSet != 0
+}
+
+// Components returns the red, green and blue components.
+func (c Color) Components() (r, g, b uint8) {
+	return uint8(c >> 16), uint8(c >> 8), uint8(c)
+}
+
+// RGBA implements color.Color, so a Color can be passed to lipgloss.
+func (c Color) RGBA() (r, g, b, a uint32) {
+	r8, g8, b8 := c.Components()
+	r, g, b = uint32(r8), uint32(g8), uint32(b8)
+	return r | r<<8, g | g<<8, b | b<<8, 0xffff
+}
+
+// String returns the color as "#rrggbb", or "unset".
+func (c Color) String() string {
+	if !c.IsSet() {
+		return "unset"
+	}
+	r, g, b := c.Components()
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+// Attrs is a set of text attributes.
+type Attrs uint8
+
+const (
+	Bold Attrs = 1 << iota
+	Italic
+	Underline
+	Reverse
+)
+
+// RichStyle is an optional per-cell style layered over the cell's
+// StyleKey style: set colors replace the keyed ones and attributes are
+// added to them. The zero value changes nothing, so cells that only use
+// keys render exactly as before.
+type RichStyle struct {
+	Fg, Bg Color
+	Attrs  Attrs
+}
+
+// IsZero reports whether the style overrides nothing.
+func (rs RichStyle) IsZero() bool {
+	return rs == RichStyle{}
+}
+
+// apply layers rs over a lipgloss style.
+func (rs RichStyle) apply(s lipgloss.Style) lipgloss.Style {
+	if rs.Fg.IsSet() {
+		s = s.Foreground(rs.Fg)
+	}
+	if rs.Bg.IsSet() {
+		s = s.Background(rs.Bg)
+	}
+	if rs.Attrs&Bold != 0 {
+		s = s.Bold(true)
+	}
+	if rs.Attrs&Italic != 0 {
+		s = s.Italic(true)
+	}
+	if rs.Attrs&Underline != 0 {
+		s = s.Underline(true)
+	}
+	if rs.Attrs&Reverse != 0 {
+		s = s.Reverse(true)
+	}
+	return s
+}
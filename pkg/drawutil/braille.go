@@ -0,0 +1,81 @@
+package drawutil
+
+import (
+	"image"
+
+	"github.com/wesen/grail/pkg/cellbuf"
+)
+
+// Each cell holds a 2×4 grid of Braille dots, so lines drawn with dots
+// have twice the horizontal and four times the vertical resolution of
+// line characters.
+const (
+	brailleW = 2
+	brailleH = 4
+)
+
+// brailleBits maps a dot's row and column within its cell to its bit in
+// the Braille pattern.
+var brailleBits = [brailleH][brailleW]uint8{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// brailleAnchor returns the dot that stands for a cell when a line runs
+// through it: left column, second row, so that horizontal lines sit near
+// the middle of the row.
+func brailleAnchor(p image.Point) image.Point {
+	return image.Pt(p.X*brailleW, p.Y*brailleH+1)
+}
+
+// PlotBraille sets the dot at (dx, dy) in dot coordinates, merging it
+// with any dots already in the cell.
+func PlotBraille(buf *cellbuf.Buffer, dx, dy int, style cellbuf.StyleKey) {
+	cx, cy := FloorDiv(dx, brailleW), FloorDiv(dy, brailleH)
+	buf.SetBraille(cx, cy, brailleBits[dy-cy*brailleH][dx-cx*brailleW], style)
+}
+
+// DrawBrailleLine draws a line between two cells with Braille dots.
+// Coordinates are buffer-local cells, as for DrawLine.
+func DrawBrailleLine(buf *cellbuf.Buffer, x0, y0, x1, y1 int, style cellbuf.StyleKey) {
+	a, b := brailleAnchor(image.Pt(x0, y0)), brailleAnchor(image.Pt(x1, y1))
+	for _, p := range Bresenham(a.X, a.Y, b.X, b.Y) {
+		PlotBraille(buf, p.X, p.Y, style)
+	}
+}
+
+// DrawBrailleArrowPolyline is DrawArrowPolyline drawn with Braille dots:
+// the segments are plotted at dot resolution, and the last vertex's cell
+// holds an arrowhead character pointing along the final segment.
+func DrawBrailleArrowPolyline(buf *cellbuf.Buffer, vertices []image.Point, lineStyle, arrowStyle cellbuf.StyleKey) {
+	if len(vertices) == 0 {
+		return
+	}
+	for i := 1; i < len(vertices); i++ {
+		a, b := vertices[i-1], vertices[i]
+		DrawBrailleLine(buf, a.X, a.Y, b.X, b.Y, lineStyle)
+	}
+
+	// Direction of the last cell step, as for line characters
+	pts := PolylinePoints(vertices)
+	last := pts[len(pts)-1]
+	var dx, dy int
+	if len(pts) >= 2 {
+		dx = last.X - pts[len(pts)-2].X
+		dy = last.Y - pts[len(pts)-2].Y
+	}
+	buf.Set(last.X, last.Y, ArrowChar(dx, dy), arrowStyle)
+}
+
+// FloorDiv divides rounding toward negative infinity, so that points
+// left of or above an origin map to negative cells: dots outside a
+// buffer, or world cells outside a camera's view.
+func FloorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,68 @@
+package drawutil
+
+import (
+	"image"
+	"testing"
+
+	"github.com/wesen/grail/pkg/cellbuf"
+)
+
+func row(buf *cellbuf.Buffer, y int) string {
+	var s []rune
+	for _, c := range buf.Cells[y] {
+		s = append(s, c.Ch)
+	}
+	return string(s)
+}
+
+func TestDrawBrailleLineHorizontal(t *testing.T) {
+	buf := cellbuf.New(5, 1, 0)
+	DrawBrailleLine(buf, 0, 0, 3, 0, 1)
+	// Dots 2 and 5 (second row, both columns); the last cell only has
+	// the anchor dot in its left column.
+	if got := row(buf, 0); got != "⠒⠒⠒⠂ " {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestDrawBrailleLineVertical(t *testing.T) {
+	buf := cellbuf.New(1, 3, 0)
+	DrawBrailleLine(buf, 0, 0, 0, 2, 1)
+	want := []rune{'⡆', '⡇', '⠃'}
+	for y, w := range want {
+		if got := buf.Cells[y][0].Ch; got != w {
+			t.Errorf("row %d: got %q, want %q", y, got, w)
+		}
+	}
+}
+
+func TestDrawBrailleLinesMerge(t *testing.T) {
+	buf := cellbuf.New(3, 3, 0)
+	DrawBrailleLine(buf, 0, 1, 2, 1, 1)
+	DrawBrailleLine(buf, 1, 0, 1, 2, 1)
+	// The crossing cell holds dots from both lines.
+	if got := buf.Cells[1][1].Ch; got != '⡗' {
+		t.Errorf("crossing: got %q, want ⡗", got)
+	}
+}
+
+func TestDrawBrailleArrowPolyline(t *testing.T) {
+	buf := cellbuf.New(6, 4, 0)
+	DrawBrailleArrowPolyline(buf, []image.Point{{0, 0}, {4, 0}, {4, 3}}, 1, 2)
+	if got := buf.Cells[3][4]; got.Ch != '▼' || got.Style != 2 {
+		t.Errorf("expected ▼ arrowhead, got %q/%d", got.Ch, got.Style)
+	}
+	for x := 0; x < 4; x++ {
+		if !cellbuf.IsBraille(buf.Cells[0][x].Ch) {
+			t.Errorf("cell (%d,0) should hold dots, got %q", x, buf.Cells[0][x].Ch)
+		}
+	}
+}
+
+func TestBrailleClipsNegative(t *testing.T) {
+	buf := cellbuf.New(2, 1, 0)
+	DrawBrailleLine(buf, -3, 0, 1, 0, 1)
+	if got := row(buf, 0); got != "⠒⠂" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -22,42 +22,97 @@ func pointChar(pts []image.Point, i int) rune {
 }
 
 // DrawLine draws a Bresenham line into buf with per-point line characters.
-// Coordinates are buffer-local (not world).
+// Coordinates are buffer-local (not world). Horizontal and vertical
+// lines join the box-drawing lines already in buf at junctions.
 func DrawLine(buf *cellbuf.Buffer, x0, y0, x1, y1 int, style cellbuf.StyleKey) {
 	pts := Bresenham(x0, y0, x1, y1)
-	for i, p := range pts {
-		buf.Set(p.X, p.Y, pointChar(pts, i), style)
+	for i := range pts {
+		setLine(buf, pts, i, style, LineStyle{}, CrossJoin)
 	}
 }
 
 // DrawArrowLine draws a line with an arrowhead at the endpoint.
 // The line uses lineStyle and the arrowhead uses arrowStyle.
 func DrawArrowLine(buf *cellbuf.Buffer, x0, y0, x1, y1 int, lineStyle, arrowStyle cellbuf.StyleKey) {
-	pts := Bresenham(x0, y0, x1, y1)
-	if len(pts) == 0 {
+	drawStroke(buf, Bresenham(x0, y0, x1, y1), Stroke{}, lineStyle, arrowStyle)
+}
+
+// DrawArrowPolyline draws connected Bresenham segments through the given
+// vertices with an arrowhead at the last one. Each cell's character
+// follows its local direction; where horizontal and vertical legs meet,
+// the corner is an elbow, and lines already in buf are joined at
+// junctions (see DrawArrowPolylineCrossing).
+func DrawArrowPolyline(buf *cellbuf.Buffer, vertices []image.Point, lineStyle, arrowStyle cellbuf.StyleKey) {
+	drawStroke(buf, PolylinePoints(vertices), Stroke{}, lineStyle, arrowStyle)
+}
+
+// DrawArrowPolylineCrossing is DrawArrowPolyline with a choice of how the
+// line shows where it crosses straight over another.
+func DrawArrowPolylineCrossing(buf *cellbuf.Buffer, vertices []image.Point, lineStyle, arrowStyle cellbuf.StyleKey, crossing Crossing) {
+	drawStroke(buf, PolylinePoints(vertices), Stroke{Crossing: crossing}, lineStyle, arrowStyle)
+}
+
+// DrawStroke draws connected Bresenham segments through the given
+// vertices like DrawArrowPolyline, in the stroke's line style and with
+// its arrowheads. Heavy and double lines join other lines with junctions
+// of their own weight; dashed lines leave gaps at corners and junctions
+// that fall in their pattern.
+func DrawStroke(buf *cellbuf.Buffer, vertices []image.Point, s Stroke, lineStyle, arrowStyle cellbuf.StyleKey) {
+	drawStroke(buf, PolylinePoints(vertices), s, lineStyle, arrowStyle)
+}
+
+// PolylinePoints returns the Bresenham points along consecutive vertices,
+// without repeating the shared vertex between segments.
+func PolylinePoints(vertices []image.Point) []image.Point {
+	if len(vertices) == 0 {
+		return nil
+	}
+	pts := []image.Point{vertices[0]}
+	for i := 1; i < len(vertices); i++ {
+		a, b := vertices[i-1], vertices[i]
+		seg := Bresenham(a.X, a.Y, b.X, b.Y)
+		pts = append(pts, seg[1:]...)
+	}
+	return pts
+}
+
+// drawStroke draws pts as a line in the stroke's style, with arrowheads
+// pointing out of the line's ends as the stroke asks.
+func drawStroke(buf *cellbuf.Buffer, pts []image.Point, s Stroke, lineStyle, arrowStyle cellbuf.StyleKey) {
+	n := len(pts)
+	if n == 0 {
 		return
 	}
+	for i := range pts {
+		if s.Line.drawn(i) {
+			setLine(buf, pts, i, lineStyle, s.Line, s.Crossing)
+		}
+	}
 
-	// Draw all points except the last as line with per-point characters
-	for i, p := range pts[:len(pts)-1] {
-		buf.Set(p.X, p.Y, pointChar(pts, i), lineStyle)
+	// Arrowheads point along the first and last steps
+	head := func(p, prev image.Point, arrow Arrow) {
+		if ch := arrow.Head(p.X-prev.X, p.Y-prev.Y); ch != 0 {
+			buf.Set(p.X, p.Y, ch, arrowStyle)
+		}
 	}
-	// Last point is arrowhead based on final segment direction
-	last := pts[len(pts)-1]
-	var dx, dy int
-	if len(pts) >= 2 {
-		dx = last.X - pts[len(pts)-2].X
-		dy = last.Y - pts[len(pts)-2].Y
+	if n == 1 {
+		head(pts[0], pts[0], s.Arrow)
+		return
+	}
+	head(pts[n-1], pts[n-2], s.Arrow)
+	if s.Arrow == ArrowBoth {
+		head(pts[0], pts[1], s.Arrow)
 	}
-	buf.Set(last.X, last.Y, ArrowChar(dx, dy), arrowStyle)
 }
 
-// DrawDashedLine draws a dashed Bresenham line (every 3rd point is
-// skipped). Used for connect-mode preview.
+// DrawDashedLine draws a dashed Bresenham line in DefaultDash (every 3rd
+// point is skipped) over whatever is in buf. Used for connect-mode
+// preview.
 func DrawDashedLine(buf *cellbuf.Buffer, x0, y0, x1, y1 int, style cellbuf.StyleKey) {
 	pts := Bresenham(x0, y0, x1, y1)
+	dashed := LineStyle{Kind: LineDashed}
 	for i, p := range pts {
-		if i%3 != 2 { // skip every 3rd point
+		if dashed.drawn(i) {
 			buf.Set(p.X, p.Y, pointChar(pts, i), style)
 		}
 	}
@@ -46,3 +46,169 @@ func EdgeExit(rect image.Rectangle, target image.Point) image.Point {
 	}
 	return image.Pt(cx, rect.Min.Y)
 }
+
+// Side identifies one side of a rectangle.
+type Side int
+
+const (
+	SideNone Side = iota
+	SideTop
+	SideRight
+	SideBottom
+	SideLeft
+)
+
+// Normal returns the outward unit vector of the side.
+func (s Side) Normal() image.Point {
+	switch s {
+	case SideTop:
+		return image.Pt(0, -1)
+	case SideRight:
+		return image.Pt(1, 0)
+	case SideBottom:
+		return image.Pt(0, 1)
+	case SideLeft:
+		return image.Pt(-1, 0)
+	}
+	return image.Point{}
+}
+
+// SideOf returns the side of rect nearest to p, measured to the border
+// cells (Min and Max-1). Ties prefer top, then bottom, left, right, so
+// corner cells belong to the top or bottom side. An empty rect yields
+// SideNone.
+func SideOf(rect image.Rectangle, p image.Point) Side {
+	if rect.Empty() {
+		return SideNone
+	}
+	best, bestDist := SideTop, abs(p.Y-rect.Min.Y)
+	for _, c := range []struct {
+		side Side
+		dist int
+	}{
+		{SideBottom, abs(rect.Max.Y - 1 - p.Y)},
+		{SideLeft, abs(p.X - rect.Min.X)},
+		{SideRight, abs(rect.Max.X - 1 - p.X)},
+	} {
+		if c.dist < bestDist {
+			best, bestDist = c.side, c.dist
+		}
+	}
+	return best
+}
+
+// PortStub returns the point one cell outside rect from a port at p, on
+// the side nearest to p. Routing an edge through the stub makes it leave
+// (or enter) the node perpendicular to that side.
+func PortStub(rect image.Rectangle, p image.Point) image.Point {
+	return p.Add(SideOf(rect, p).Normal())
+}
+
+// OrthogonalRoute replaces each diagonal segment of route with
+// horizontal and vertical legs. A segment sets off in the direction of
+// the one before it and arrives in the direction of the one after it;
+// the first leaves from's side and the last enters to's side
+// perpendicularly. When the two directions differ the segment becomes an
+// L, otherwise a Z that turns halfway.
+func OrthogonalRoute(route []image.Point, from, to image.Rectangle) []image.Point {
+	if len(route) < 2 {
+		return route
+	}
+	out := []image.Point{route[0]}
+	for i := 1; i < len(route); i++ {
+		a, b := route[i-1], route[i]
+		if a.X != b.X && a.Y != b.Y {
+			var startH, endH bool
+			if i >= 2 {
+				startH = route[i-2].Y == a.Y
+			} else {
+				startH = leavesHorizontally(from, a, b)
+			}
+			if i+1 < len(route) {
+				endH = route[i+1].Y == b.Y
+			} else {
+				endH = leavesHorizontally(to, b, a)
+			}
+			switch {
+			case startH && endH:
+				mx := (a.X + b.X) / 2
+				out = append(out, image.Pt(mx, a.Y), image.Pt(mx, b.Y))
+			case !startH && !endH:
+				my := (a.Y + b.Y) / 2
+				out = append(out, image.Pt(a.X, my), image.Pt(b.X, my))
+			case startH:
+				out = append(out, image.Pt(b.X, a.Y))
+			default:
+				out = append(out, image.Pt(a.X, b.Y))
+			}
+		}
+		out = append(out, b)
+	}
+	return dedupPoints(out)
+}
+
+// leavesHorizontally reports whether a line from p on rect's border
+// toward target leaves through the left or right side. Without a side,
+// the dominant direction to target decides.
+func leavesHorizontally(rect image.Rectangle, p, target image.Point) bool {
+	switch SideOf(rect, p) {
+	case SideLeft, SideRight:
+		return true
+	case SideTop, SideBottom:
+		return false
+	}
+	return abs(target.X-p.X) >= abs(target.Y-p.Y)
+}
+
+// dedupPoints drops consecutive repeated points.
+func dedupPoints(pts []image.Point) []image.Point {
+	out := pts[:1]
+	for _, p := range pts[1:] {
+		if p != out[len(out)-1] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Size of a self-loop's hook: how far it reaches out from the node, and
+// how many cells it spans along a top or bottom side.
+const (
+	loopReach = 2
+	loopSpan  = 4
+)
+
+// SelfLoopRoute returns the route of an edge from a node to itself: a
+// rectangular hook that leaves rect through side and comes back to it.
+// On the left and right it spans the side from corner to corner; on the
+// top and bottom it spans loopSpan cells near the right-hand corner.
+func SelfLoopRoute(rect image.Rectangle, side Side) []image.Point {
+	var a, b image.Point
+	switch side {
+	case SideLeft, SideRight:
+		x := rect.Min.X
+		if side == SideRight {
+			x = rect.Max.X - 1
+		}
+		a, b = image.Pt(x, rect.Min.Y), image.Pt(x, rect.Max.Y-1)
+	case SideTop, SideBottom:
+		y := rect.Min.Y
+		if side == SideBottom {
+			y = rect.Max.Y - 1
+		}
+		b = image.Pt(max(rect.Max.X-2, rect.Min.X), y)
+		a = image.Pt(max(b.X-loopSpan, rect.Min.X), y)
+	default:
+		return nil
+	}
+	out := side.Normal().Mul(loopReach)
+	return []image.Point{a, a.Add(out), b.Add(out), b}
+}
+
+// RailRoute returns a route from p1 to p2 that runs straight across to
+// the vertical rail at x = railX, along it, and straight back. Routing
+// back-edges along a rail beside the chart keeps them from running over
+// the forward edges.
+func RailRoute(p1, p2 image.Point, railX int) []image.Point {
+	return dedupPoints([]image.Point{p1, image.Pt(railX, p1.Y), image.Pt(railX, p2.Y), p2})
+}
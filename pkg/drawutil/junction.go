@@ -0,0 +1,153 @@
+package drawutil
+
+import (
+	"image"
+
+	"github.com/wesen/grail/pkg/cellbuf"
+)
+
+// Crossing selects how a line drawn straight across another is shown.
+type Crossing int
+
+const (
+	CrossJoin Crossing = iota // the lines join in a ┼
+	CrossHop                  // the horizontal line hops over the vertical one: HopGlyph
+)
+
+// HopGlyph marks a horizontal line hopping over a vertical one.
+const HopGlyph = '⌒'
+
+// Directions in which a line leaves a cell. A box-drawing character is
+// the set of directions it connects.
+const (
+	dirUp uint8 = 1 << iota
+	dirDown
+	dirLeft
+	dirRight
+
+	dirsH   = dirLeft | dirRight
+	dirsV   = dirUp | dirDown
+	dirsAll = dirsH | dirsV
+)
+
+// weight is a set of box-drawing characters.
+type weight int
+
+const (
+	weightLight  weight = iota // ─ │ ┌ ┼
+	weightHeavy                // ━ ┃ ┏ ╋
+	weightDouble               // ═ ║ ╔ ╬
+)
+
+// boxGlyphs maps a set of directions to its box-drawing character in
+// each weight. A single direction draws a full line through the cell.
+var boxGlyphs = [...]map[uint8]rune{
+	weightLight:  makeBoxGlyphs("─│┌┐└┘├┤┬┴┼"),
+	weightHeavy:  makeBoxGlyphs("━┃┏┓┗┛┣┫┳┻╋"),
+	weightDouble: makeBoxGlyphs("═║╔╗╚╝╠╣╦╩╬"),
+}
+
+// boxDirs lists direction sets in the order makeBoxGlyphs takes their
+// characters.
+var boxDirs = []uint8{
+	dirsH, dirsV,
+	dirDown | dirRight, dirDown | dirLeft, dirUp | dirRight, dirUp | dirLeft,
+	dirsV | dirRight, dirsV | dirLeft, dirsH | dirDown, dirsH | dirUp,
+	dirsAll,
+}
+
+// makeBoxGlyphs maps the direction sets in boxDirs to the characters of
+// chars, in the same order.
+func makeBoxGlyphs(chars string) map[uint8]rune {
+	m := make(map[uint8]rune)
+	for i, ch := range []rune(chars) {
+		m[boxDirs[i]] = ch
+	}
+	m[dirUp], m[dirDown] = m[dirsV], m[dirsV]
+	m[dirLeft], m[dirRight] = m[dirsH], m[dirsH]
+	return m
+}
+
+// glyphDirs maps the characters lines merge with, in any weight, to
+// their directions.
+var glyphDirs = func() map[rune]uint8 {
+	m := map[rune]uint8{'┈': dirsH, '┊': dirsV, HopGlyph: dirsAll}
+	for _, glyphs := range boxGlyphs {
+		for _, dirs := range boxDirs {
+			m[glyphs[dirs]] = dirs
+		}
+	}
+	return m
+}()
+
+// stepDir returns the direction of a single axis-aligned step, or 0 for
+// a diagonal or empty one.
+func stepDir(d image.Point) uint8 {
+	switch d {
+	case image.Pt(0, -1):
+		return dirUp
+	case image.Pt(0, 1):
+		return dirDown
+	case image.Pt(-1, 0):
+		return dirLeft
+	case image.Pt(1, 0):
+		return dirRight
+	}
+	return 0
+}
+
+// pointDirs returns the directions in which the line through pts[i]
+// leaves its cell toward its neighbours, so that a corner gets an elbow.
+// It returns 0 if a neighbouring step is diagonal or there is none.
+func pointDirs(pts []image.Point, i int) uint8 {
+	var dirs uint8
+	if i > 0 {
+		d := stepDir(pts[i-1].Sub(pts[i]))
+		if d == 0 {
+			return 0
+		}
+		dirs |= d
+	}
+	if i < len(pts)-1 {
+		d := stepDir(pts[i+1].Sub(pts[i]))
+		if d == 0 {
+			return 0
+		}
+		dirs |= d
+	}
+	return dirs
+}
+
+// setLine draws the line character for pts[i] in the given line style.
+// An axis-aligned line is merged with a box-drawing line already in the
+// cell into the junction glyph connecting both, so a line ending on
+// another makes a ├ ┤ ┬ ┴; a straight crossing becomes a hop when asked.
+// Junctions take the weight of the line drawn last. Diagonal lines
+// overwrite the cell.
+func setLine(buf *cellbuf.Buffer, pts []image.Point, i int, style cellbuf.StyleKey, line LineStyle, crossing Crossing) {
+	p := pts[i]
+	dirs := pointDirs(pts, i)
+	if dirs == 0 {
+		buf.Set(p.X, p.Y, pointChar(pts, i), style)
+		return
+	}
+	if buf.InBounds(p.X, p.Y) {
+		if old, ok := glyphDirs[buf.Cells[p.Y][p.X].Ch]; ok {
+			if crossing == CrossHop && (old == dirsV && dirs == dirsH || old == dirsH && dirs == dirsV) {
+				buf.Set(p.X, p.Y, HopGlyph, style)
+				return
+			}
+			dirs |= old
+		}
+	}
+	ch := boxGlyphs[line.weight()][dirs]
+	if line.Kind == LineDotted {
+		switch ch {
+		case '─':
+			ch = '┈'
+		case '│':
+			ch = '┊'
+		}
+	}
+	buf.Set(p.X, p.Y, ch, style)
+}
@@ -0,0 +1,127 @@
+package drawutil
+
+import (
+	"image"
+	"slices"
+	"testing"
+
+	"github.com/wesen/grail/pkg/cellbuf"
+)
+
+func TestDrawLineJoinsCrossing(t *testing.T) {
+	buf := cellbuf.New(5, 5, 0)
+	DrawLine(buf, 2, 0, 2, 4, 1)
+	DrawLine(buf, 0, 2, 4, 2, 2)
+	if c := buf.Cells[2][2]; c.Ch != '┼' || c.Style != 2 {
+		t.Errorf("crossing: expected ┼/2, got %c/%d", c.Ch, c.Style)
+	}
+	if c := buf.Cells[1][2]; c.Ch != '│' {
+		t.Errorf("first line should stay intact, got %c", c.Ch)
+	}
+}
+
+func TestDrawLineTeeJunctions(t *testing.T) {
+	tests := []struct {
+		x0, y0, x1, y1 int
+		want           rune
+	}{
+		{2, 2, 4, 2, '├'}, // branch to the right of a vertical line
+		{0, 2, 2, 2, '┤'},
+		{2, 0, 2, 2, '┴'}, // branch up from a horizontal line
+		{2, 2, 2, 4, '┬'},
+	}
+	for _, tc := range tests {
+		buf := cellbuf.New(5, 5, 0)
+		if tc.y0 == tc.y1 {
+			DrawLine(buf, 2, 0, 2, 4, 1)
+		} else {
+			DrawLine(buf, 0, 2, 4, 2, 1)
+		}
+		DrawLine(buf, tc.x0, tc.y0, tc.x1, tc.y1, 1)
+		if got := buf.Cells[2][2].Ch; got != tc.want {
+			t.Errorf("line (%d,%d)-(%d,%d): got %c, want %c", tc.x0, tc.y0, tc.x1, tc.y1, got, tc.want)
+		}
+	}
+}
+
+func TestDrawArrowPolylineCorners(t *testing.T) {
+	buf := cellbuf.New(6, 4, 0)
+	DrawArrowPolyline(buf, []image.Point{{0, 0}, {4, 0}, {4, 2}, {1, 2}, {1, 3}}, 1, 2)
+	for _, tc := range []struct {
+		p    image.Point
+		want rune
+	}{
+		{image.Pt(4, 0), '┐'},
+		{image.Pt(4, 2), '┘'},
+		{image.Pt(1, 2), '┌'},
+		{image.Pt(1, 3), '▼'},
+	} {
+		if got := buf.Cells[tc.p.Y][tc.p.X].Ch; got != tc.want {
+			t.Errorf("%v: got %c, want %c", tc.p, got, tc.want)
+		}
+	}
+}
+
+func TestDrawArrowPolylineHop(t *testing.T) {
+	buf := cellbuf.New(5, 5, 0)
+	DrawArrowPolylineCrossing(buf, []image.Point{{2, 0}, {2, 4}}, 1, 1, CrossHop)
+	DrawArrowPolylineCrossing(buf, []image.Point{{0, 2}, {4, 2}}, 1, 1, CrossHop)
+	if got := buf.Cells[2][2].Ch; got != HopGlyph {
+		t.Errorf("crossing: expected hop, got %c", got)
+	}
+
+	// A branch is still a junction, since nothing is hopped over.
+	buf = cellbuf.New(5, 5, 0)
+	DrawArrowPolylineCrossing(buf, []image.Point{{2, 0}, {2, 4}}, 1, 1, CrossHop)
+	DrawArrowPolylineCrossing(buf, []image.Point{{2, 2}, {4, 2}}, 1, 1, CrossHop)
+	if got := buf.Cells[2][2].Ch; got != '├' {
+		t.Errorf("branch: expected ├, got %c", got)
+	}
+}
+
+func TestOrthogonalRoute(t *testing.T) {
+	from := image.Rect(0, 0, 10, 3)
+	tests := []struct {
+		name  string
+		route []image.Point
+		to    image.Rectangle
+		want  []image.Point
+	}{
+		{
+			name:  "right side to left side: Z through the middle column",
+			route: []image.Point{{9, 1}, {20, 7}},
+			to:    image.Rect(20, 6, 30, 9),
+			want:  []image.Point{{9, 1}, {14, 1}, {14, 7}, {20, 7}},
+		},
+		{
+			name:  "bottom to top: Z through the middle row",
+			route: []image.Point{{5, 2}, {15, 10}},
+			to:    image.Rect(10, 10, 20, 13),
+			want:  []image.Point{{5, 2}, {5, 6}, {15, 6}, {15, 10}},
+		},
+		{
+			name:  "right side to top: L",
+			route: []image.Point{{9, 1}, {25, 10}},
+			to:    image.Rect(20, 10, 30, 13),
+			want:  []image.Point{{9, 1}, {25, 1}, {25, 10}},
+		},
+		{
+			name:  "port stubs keep their direction",
+			route: []image.Point{{5, 2}, {5, 3}, {19, 8}, {20, 8}},
+			to:    image.Rect(20, 6, 30, 11),
+			want:  []image.Point{{5, 2}, {5, 3}, {5, 8}, {19, 8}, {20, 8}},
+		},
+		{
+			name:  "straight segments are unchanged",
+			route: []image.Point{{9, 1}, {20, 1}},
+			to:    image.Rect(20, 0, 30, 3),
+			want:  []image.Point{{9, 1}, {20, 1}},
+		},
+	}
+	for _, tc := range tests {
+		got := OrthogonalRoute(tc.route, from, tc.to)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
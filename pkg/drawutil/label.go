@@ -0,0 +1,114 @@
+package drawutil
+
+import "image"
+
+// Overlap costs per cell when placing labels: covering a node is worst,
+// then covering another label, then covering a line.
+const (
+	costNode  = 8
+	costLabel = 4
+	costLine  = 1
+)
+
+// LabelPlacer places one-row edge labels along their edges' drawn paths,
+// clear of nodes, lines and the labels it placed before. Place labels in
+// a fixed order so that the layout is stable from frame to frame.
+type LabelPlacer struct {
+	nodes  []image.Rectangle
+	lines  map[image.Point]bool
+	labels []image.Rectangle
+}
+
+// NewLabelPlacer returns a placer that keeps labels off the given node
+// rectangles. Add the drawn lines with AddLine before placing labels.
+func NewLabelPlacer(nodes []image.Rectangle) *LabelPlacer {
+	return &LabelPlacer{nodes: nodes, lines: make(map[image.Point]bool)}
+}
+
+// AddLine marks the cells of a line, such as an edge's drawn path, so
+// that labels avoid covering them.
+func (lp *LabelPlacer) AddLine(pts []image.Point) {
+	for _, p := range pts {
+		lp.lines[p] = true
+	}
+}
+
+// Place returns the rectangle for a label w cells wide beside path, the
+// cells of an edge as drawn (see PolylinePoints). Positions next to the
+// middle of the path are tried first, working outwards: above a
+// horizontal stretch, then below it; right of a vertical or diagonal
+// stretch, then left of it. The first position that covers nothing is
+// taken. When every position covers something, the one that covers the
+// least is, so a label is always placed. The result is remembered for
+// later calls. An empty path gives an empty rectangle.
+func (lp *LabelPlacer) Place(path []image.Point, w int) image.Rectangle {
+	if len(path) == 0 || w <= 0 {
+		return image.Rectangle{}
+	}
+	var best image.Rectangle
+	bestCost := -1
+	mid := (len(path) - 1) / 2
+	for step := 0; step < len(path) && bestCost != 0; step++ {
+		// mid, mid+1, mid-1, mid+2, ...
+		i := mid + (step+1)/2
+		if step%2 == 0 {
+			i = mid - step/2
+		}
+		if i < 0 || i >= len(path) {
+			continue
+		}
+		for _, r := range labelCandidates(path, i, w) {
+			if cost := lp.cost(r); bestCost < 0 || cost < bestCost {
+				best, bestCost = r, cost
+				if cost == 0 {
+					break
+				}
+			}
+		}
+	}
+	lp.labels = append(lp.labels, best)
+	return best
+}
+
+// labelCandidates returns the label positions beside path[i], in order
+// of preference.
+func labelCandidates(path []image.Point, i, w int) []image.Rectangle {
+	p := path[i]
+	var d image.Point
+	switch {
+	case i+1 < len(path):
+		d = path[i+1].Sub(p)
+	case i > 0:
+		d = p.Sub(path[i-1])
+	}
+	at := func(x, y int) image.Rectangle { return image.Rect(x, y, x+w, y+1) }
+	if d.Y == 0 {
+		x := p.X - w/2
+		return []image.Rectangle{at(x, p.Y-1), at(x, p.Y+1)}
+	}
+	return []image.Rectangle{at(p.X+1, p.Y), at(p.X-w, p.Y)}
+}
+
+// cost weighs what a label at r would cover.
+func (lp *LabelPlacer) cost(r image.Rectangle) int {
+	cost := 0
+	for _, n := range lp.nodes {
+		cost += costNode * area(r.Intersect(n))
+	}
+	for _, l := range lp.labels {
+		cost += costLabel * area(r.Intersect(l))
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if lp.lines[image.Pt(x, y)] {
+				cost += costLine
+			}
+		}
+	}
+	return cost
+}
+
+// area returns the number of cells in r.
+func area(r image.Rectangle) int {
+	return r.Dx() * r.Dy()
+}
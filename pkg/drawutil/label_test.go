@@ -0,0 +1,76 @@
+package drawutil
+
+import (
+	"image"
+	"testing"
+)
+
+func TestLabelPlacerMiddle(t *testing.T) {
+	path := PolylinePoints([]image.Point{{0, 5}, {10, 5}})
+	lp := NewLabelPlacer(nil)
+	lp.AddLine(path)
+
+	// Above the middle of a horizontal line, centred
+	if got, want := lp.Place(path, 3), image.Rect(4, 4, 7, 5); got != want {
+		t.Errorf("horizontal: got %v, want %v", got, want)
+	}
+
+	// Right of the middle of a vertical line
+	vert := PolylinePoints([]image.Point{{20, 0}, {20, 10}})
+	lp.AddLine(vert)
+	if got, want := lp.Place(vert, 1), image.Rect(21, 5, 22, 6); got != want {
+		t.Errorf("vertical: got %v, want %v", got, want)
+	}
+}
+
+func TestLabelPlacerAvoidsNodesAndLabels(t *testing.T) {
+	path := PolylinePoints([]image.Point{{0, 5}, {10, 5}})
+
+	// A node above the middle pushes the label below the line
+	lp := NewLabelPlacer([]image.Rectangle{image.Rect(0, 2, 11, 5)})
+	lp.AddLine(path)
+	if got, want := lp.Place(path, 3), image.Rect(4, 6, 7, 7); got != want {
+		t.Errorf("node above: got %v, want %v", got, want)
+	}
+
+	// The same label again must not land on the first one
+	first := lp.labels[0]
+	if got := lp.Place(path, 3); got.Overlaps(first) {
+		t.Errorf("second label %v overlaps first %v", got, first)
+	}
+}
+
+func TestLabelPlacerAvoidsLines(t *testing.T) {
+	path := PolylinePoints([]image.Point{{0, 5}, {10, 5}})
+	lp := NewLabelPlacer(nil)
+	lp.AddLine(path)
+	// Another line runs just above and below the middle of the path
+	lp.AddLine(PolylinePoints([]image.Point{{3, 4}, {7, 4}}))
+	lp.AddLine(PolylinePoints([]image.Point{{3, 6}, {7, 6}}))
+
+	got := lp.Place(path, 3)
+	for y := got.Min.Y; y < got.Max.Y; y++ {
+		for x := got.Min.X; x < got.Max.X; x++ {
+			if lp.lines[image.Pt(x, y)] {
+				t.Fatalf("label %v covers a line at (%d,%d)", got, x, y)
+			}
+		}
+	}
+}
+
+func TestLabelPlacerFallback(t *testing.T) {
+	// Everything around the path is a node: the label still gets the
+	// position that covers the least, and is remembered.
+	path := PolylinePoints([]image.Point{{0, 5}, {4, 5}})
+	lp := NewLabelPlacer([]image.Rectangle{image.Rect(-10, 0, 20, 5), image.Rect(-10, 6, 20, 10)})
+	got := lp.Place(path, 2)
+	if got.Empty() {
+		t.Fatal("expected a placement when no position is clear")
+	}
+	if len(lp.labels) != 1 {
+		t.Errorf("expected the placement to be remembered, have %d", len(lp.labels))
+	}
+	if got := lp.Place(nil, 2); !got.Empty() {
+		t.Errorf("empty path: expected an empty rectangle, got %v", got)
+	}
+}
@@ -2,6 +2,7 @@ package drawutil
 
 import (
 	"image"
+	"slices"
 	"testing"
 
 	"github.com/wesen/grail/pkg/cellbuf"
@@ -174,6 +175,40 @@ func TestEdgeExitSameCenter(t *testing.T) {
 	}
 }
 
+// ── Sides and ports ──
+
+func TestSideOf(t *testing.T) {
+	rect := image.Rect(10, 10, 32, 13) // 22 wide, 3 tall
+	tests := []struct {
+		p    image.Point
+		want Side
+	}{
+		{image.Pt(21, 10), SideTop},
+		{image.Pt(21, 12), SideBottom},
+		{image.Pt(10, 11), SideLeft},
+		{image.Pt(31, 11), SideRight},
+		{image.Pt(10, 10), SideTop}, // corner
+	}
+	for _, tc := range tests {
+		if got := SideOf(rect, tc.p); got != tc.want {
+			t.Errorf("SideOf(%v) = %d, want %d", tc.p, got, tc.want)
+		}
+	}
+	if SideOf(image.Rectangle{}, image.Pt(0, 0)) != SideNone {
+		t.Error("empty rect should yield SideNone")
+	}
+}
+
+func TestPortStub(t *testing.T) {
+	rect := image.Rect(10, 10, 32, 13)
+	if got := PortStub(rect, image.Pt(21, 12)); got != image.Pt(21, 13) {
+		t.Errorf("bottom port stub: expected (21,13), got %v", got)
+	}
+	if got := PortStub(rect, image.Pt(31, 11)); got != image.Pt(32, 11) {
+		t.Errorf("right port stub: expected (32,11), got %v", got)
+	}
+}
+
 // ── Draw functions ──
 
 func TestDrawLine(t *testing.T) {
@@ -212,6 +247,33 @@ func TestDrawArrowLine(t *testing.T) {
 	}
 }
 
+func TestPolylinePoints(t *testing.T) {
+	pts := PolylinePoints([]image.Point{{0, 0}, {3, 0}, {3, 2}})
+	want := []image.Point{{0, 0}, {1, 0}, {2, 0}, {3, 0}, {3, 1}, {3, 2}}
+	if len(pts) != len(want) {
+		t.Fatalf("expected %d points, got %d: %v", len(want), len(pts), pts)
+	}
+	for i := range want {
+		if pts[i] != want[i] {
+			t.Errorf("point %d: expected %v, got %v", i, want[i], pts[i])
+		}
+	}
+}
+
+func TestDrawArrowPolyline(t *testing.T) {
+	buf := cellbuf.New(10, 10, 0)
+	DrawArrowPolyline(buf, []image.Point{{0, 0}, {4, 0}, {4, 3}}, 1, 2)
+	if c := buf.Cells[0][2]; c.Ch != '─' || c.Style != 1 {
+		t.Errorf("horizontal leg: expected ─/1, got %c/%d", c.Ch, c.Style)
+	}
+	if c := buf.Cells[2][4]; c.Ch != '│' {
+		t.Errorf("vertical leg: expected │, got %c", c.Ch)
+	}
+	if c := buf.Cells[3][4]; c.Ch != '▼' || c.Style != 2 {
+		t.Errorf("arrowhead: expected ▼/2, got %c/%d", c.Ch, c.Style)
+	}
+}
+
 func TestDrawDashedLine(t *testing.T) {
 	buf := cellbuf.New(20, 1, 0)
 	DrawDashedLine(buf, 0, 0, 19, 0, 1)
@@ -263,3 +325,56 @@ func TestDrawGridWithCamera(t *testing.T) {
 		t.Error("grid+cam: expected dot at buf(3,2) = world(5,3)")
 	}
 }
+
+// ── Self-loops and back-edges ──
+
+func TestSelfLoopRoute(t *testing.T) {
+	rect := image.Rect(10, 5, 20, 8)
+	tests := []struct {
+		side Side
+		want []image.Point
+	}{
+		{SideRight, []image.Point{{19, 5}, {21, 5}, {21, 7}, {19, 7}}},
+		{SideLeft, []image.Point{{10, 5}, {8, 5}, {8, 7}, {10, 7}}},
+		{SideTop, []image.Point{{14, 5}, {14, 3}, {18, 3}, {18, 5}}},
+		{SideBottom, []image.Point{{14, 7}, {14, 9}, {18, 9}, {18, 7}}},
+		{SideNone, nil},
+	}
+	for _, tc := range tests {
+		if got := SelfLoopRoute(rect, tc.side); !slices.Equal(got, tc.want) {
+			t.Errorf("side %d: got %v, want %v", tc.side, got, tc.want)
+		}
+	}
+
+	// The hook is drawn as box lines outside the node
+	buf := cellbuf.New(25, 10, 0)
+	DrawArrowPolyline(buf, SelfLoopRoute(rect, SideRight), 1, 1)
+	for _, tc := range []struct {
+		p    image.Point
+		want rune
+	}{
+		{image.Pt(20, 5), '─'},
+		{image.Pt(21, 5), '┐'},
+		{image.Pt(21, 6), '│'},
+		{image.Pt(21, 7), '┘'},
+		{image.Pt(19, 7), '◄'},
+	} {
+		if got := buf.Cells[tc.p.Y][tc.p.X].Ch; got != tc.want {
+			t.Errorf("hook at %v: got %c, want %c", tc.p, got, tc.want)
+		}
+	}
+}
+
+func TestRailRoute(t *testing.T) {
+	got := RailRoute(image.Pt(30, 14), image.Pt(4, 10), 1)
+	want := []image.Point{{30, 14}, {1, 14}, {1, 10}, {4, 10}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Ends on the rail add no zero-length legs
+	got = RailRoute(image.Pt(1, 14), image.Pt(4, 10), 1)
+	want = []image.Point{{1, 14}, {1, 10}, {4, 10}}
+	if !slices.Equal(got, want) {
+		t.Errorf("end on rail: got %v, want %v", got, want)
+	}
+}
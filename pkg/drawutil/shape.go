@@ -0,0 +1,190 @@
+package drawutil
+
+import (
+	"image"
+	"math"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/wesen/grail/pkg/cellbuf"
+)
+
+// Shape is the outline of a flowchart symbol (ISO 5807).
+type Shape int
+
+const (
+	ShapeBox           Shape = iota // process
+	ShapeRounded                    // terminal
+	ShapeDiamond                    // decision
+	ShapeParallelogram              // input/output
+	ShapeHexagon                    // preparation
+	ShapeCircle                     // connector
+)
+
+// ShapeSpan returns the columns x0 and x1 (inclusive) where the outline
+// of shape crosses row y of r. ok is false outside r. Shapes are convex,
+// so every cell between x0 and x1 is inside.
+func ShapeSpan(r image.Rectangle, shape Shape, y int) (x0, x1 int, ok bool) {
+	if r.Empty() || y < r.Min.Y || y >= r.Max.Y {
+		return 0, 0, false
+	}
+	left, right := shapeInsets(r.Dx(), r.Dy(), shape, y-r.Min.Y)
+	return r.Min.X + left, r.Max.X - 1 - right, true
+}
+
+// shapeInsets returns how far the outline on row i of a w×h shape is in
+// from the left and right of its rectangle.
+func shapeInsets(w, h int, shape Shape, i int) (left, right int) {
+	// d is the distance of the row from the middle, in half rows
+	d := abs(2*i - (h - 1))
+	switch shape {
+	case ShapeParallelogram:
+		return h - 1 - i, i
+	case ShapeHexagon:
+		return d / 2, d / 2
+	case ShapeDiamond:
+		in := w * d / (2 * h)
+		return in, in
+	case ShapeCircle:
+		t := float64(d) / float64(h)
+		in := int(math.Round(float64(w) / 2 * (1 - math.Sqrt(1-t*t))))
+		return in, in
+	}
+	return 0, 0
+}
+
+// shapeEdges returns the outline characters at the left and right ends
+// of row i, from how the row's width changes toward its neighbours.
+func shapeEdges(w, h int, shape Shape, i int) (left, right rune) {
+	top, bottom := i == 0, i == h-1
+	switch shape {
+	case ShapeBox, ShapeRounded:
+		corners := "┌┐└┘"
+		if shape == ShapeRounded {
+			corners = "╭╮╰╯"
+		}
+		c := []rune(corners)
+		switch {
+		case top:
+			return c[0], c[1]
+		case bottom:
+			return c[2], c[3]
+		}
+		return '│', '│'
+	case ShapeParallelogram:
+		return '╱', '╱'
+	}
+
+	// Neighbours beyond the top and bottom count as further in
+	in, _ := shapeInsets(w, h, shape, i)
+	prev, next := w, w
+	if !top {
+		prev, _ = shapeInsets(w, h, shape, i-1)
+	}
+	if !bottom {
+		next, _ = shapeInsets(w, h, shape, i+1)
+	}
+	round := shape == ShapeCircle
+	switch {
+	case prev > in && next > in:
+		if round {
+			return '(', ')'
+		}
+		return '<', '>'
+	case next < in:
+		if round && top {
+			return '╭', '╮'
+		}
+		return '╱', '╲'
+	case prev < in:
+		if round && bottom {
+			return '╰', '╯'
+		}
+		return '╲', '╱'
+	case top:
+		return '┌', '┐'
+	case bottom:
+		return '└', '┘'
+	}
+	return '│', '│'
+}
+
+// DrawShape draws the outline of shape in r with the border style, fills
+// its inside with spaces in the fill style, and centres text in it in
+// the text style. The text is word-wrapped to the width of the middle
+// row; lines that do not fit between the top and bottom rows are
+// dropped, and each line is cut to the width of the row it is on. Cells
+// of r outside the shape are left as they are.
+func DrawShape(buf *cellbuf.Buffer, r image.Rectangle, shape Shape, text string,
+	border, fill, textStyle cellbuf.StyleKey) {
+
+	w, h := r.Dx(), r.Dy()
+	if w <= 0 || h <= 0 {
+		return
+	}
+	for i := range h {
+		y := r.Min.Y + i
+		x0, x1, _ := ShapeSpan(r, shape, y)
+		left, right := shapeEdges(w, h, shape, i)
+		inner, innerStyle := ' ', fill
+		if i == 0 || i == h-1 {
+			inner, innerStyle = '─', border
+		}
+		for x := x0 + 1; x < x1; x++ {
+			buf.Set(x, y, inner, innerStyle)
+		}
+		buf.Set(x0, y, left, border)
+		if x1 > x0 {
+			buf.Set(x1, y, right, border)
+		}
+	}
+
+	rows := h - 2
+	if text == "" || rows <= 0 {
+		return
+	}
+	mid := r.Min.Y + h/2
+	x0, x1, _ := ShapeSpan(r, shape, mid)
+	lines := WrapText(text, x1-x0-3)
+	if len(lines) > rows {
+		lines = lines[:rows]
+	}
+	top := r.Min.Y + 1 + (rows-len(lines))/2
+	for i, line := range lines {
+		y := top + i
+		x0, x1, _ := ShapeSpan(r, shape, y)
+		line = ansi.Truncate(line, max(x1-x0-3, 0), "")
+		lw := ansi.StringWidth(line)
+		buf.SetString(x0+2+(x1-x0-3-lw)/2, y, line, textStyle)
+	}
+}
+
+// WrapText breaks text into lines of at most width cells, between words
+// where it can. It returns nil for empty text or a width below one.
+func WrapText(text string, width int) []string {
+	if text == "" || width < 1 {
+		return nil
+	}
+	lines := strings.Split(ansi.Wrap(text, width, ""), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	return lines
+}
+
+// ShapeExit is EdgeExit for the outline of shape rather than its
+// rectangle: an exit through the left or right side is moved in to the
+// outline on its row. Exits through the top and bottom, at the centre
+// column, are on the outline of every shape already.
+func ShapeExit(rect image.Rectangle, shape Shape, target image.Point) image.Point {
+	p := EdgeExit(rect, target)
+	x0, x1, ok := ShapeSpan(rect, shape, p.Y)
+	switch {
+	case !ok:
+	case p.X == rect.Min.X:
+		p.X = x0
+	case p.X == rect.Max.X-1:
+		p.X = x1
+	}
+	return p
+}
@@ -0,0 +1,128 @@
+package drawutil
+
+import (
+	"image"
+	"slices"
+	"testing"
+
+	"github.com/wesen/grail/pkg/cellbuf"
+)
+
+// drawShape draws shape with text in a w×h buffer and returns its rows.
+func drawShape(shape Shape, w, h int, text string) []string {
+	buf := cellbuf.New(w, h, 0)
+	DrawShape(buf, image.Rect(0, 0, w, h), shape, text, 1, 2, 3)
+	rows := make([]string, h)
+	for y := range h {
+		rows[y] = row(buf, y)
+	}
+	return rows
+}
+
+func TestDrawShapeOutlines(t *testing.T) {
+	tests := []struct {
+		shape Shape
+		want  []string
+	}{
+		{ShapeBox, []string{"┌──────────┐", "│   TEXT   │", "└──────────┘"}},
+		{ShapeRounded, []string{"╭──────────╮", "│   TEXT   │", "╰──────────╯"}},
+		{ShapeDiamond, []string{"    ╱──╲    ", "<   TEXT   >", "    ╲──╱    "}},
+		{ShapeParallelogram, []string{"  ╱─────────╱", " ╱  TEXT   ╱ ", "╱─────────╱  "}},
+		{ShapeHexagon, []string{" ╱────────╲ ", "<   TEXT   >", " ╲────────╱ "}},
+		{ShapeCircle, []string{"  ╭──────╮  ", "(   TEXT   )", "  ╰──────╯  "}},
+	}
+	for _, tc := range tests {
+		w := 12
+		if tc.shape == ShapeParallelogram {
+			w = 13
+		}
+		if got := drawShape(tc.shape, w, 3, "TEXT"); !slices.Equal(got, tc.want) {
+			t.Errorf("shape %d:\n got %q\nwant %q", tc.shape, got, tc.want)
+		}
+	}
+}
+
+func TestDrawShapeStyles(t *testing.T) {
+	buf := cellbuf.New(12, 3, 0)
+	DrawShape(buf, image.Rect(0, 0, 12, 3), ShapeDiamond, "TEXT", 1, 2, 3)
+	for _, tc := range []struct {
+		x, y int
+		want cellbuf.StyleKey
+	}{
+		{0, 1, 1}, // outline
+		{2, 1, 2}, // fill
+		{4, 1, 3}, // text
+		{5, 0, 1}, // top edge
+		{0, 0, 0}, // outside the shape: untouched
+	} {
+		if got := buf.Cells[tc.y][tc.x].Style; got != tc.want {
+			t.Errorf("(%d,%d): style %d, want %d", tc.x, tc.y, got, tc.want)
+		}
+	}
+}
+
+func TestDrawShapeWrapsText(t *testing.T) {
+	got := drawShape(ShapeBox, 12, 5, "ONE TWO THREE FOUR")
+	want := []string{
+		"┌──────────┐",
+		"│ ONE TWO  │",
+		"│  THREE   │",
+		"│   FOUR   │",
+		"└──────────┘",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("wrapped:\n got %q\nwant %q", got, want)
+	}
+
+	// Lines beyond the inner rows are dropped
+	got = drawShape(ShapeBox, 12, 3, "ONE TWO THREE FOUR")
+	if got[1] != "│ ONE TWO  │" {
+		t.Errorf("clipped: got %q", got[1])
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{"sum = sum + i", 8, []string{"sum =", "sum + i"}},
+		{"ACCUMULATE", 4, []string{"ACCU", "MULA", "TE"}},
+		{"short", 10, []string{"short"}},
+		{"", 10, nil},
+		{"x", 0, nil},
+	}
+	for _, tc := range tests {
+		if got := WrapText(tc.text, tc.width); !slices.Equal(got, tc.want) {
+			t.Errorf("WrapText(%q, %d) = %q, want %q", tc.text, tc.width, got, tc.want)
+		}
+	}
+}
+
+func TestShapeExit(t *testing.T) {
+	r := image.Rect(10, 10, 23, 13) // 13×3
+	tests := []struct {
+		shape  Shape
+		target image.Point
+		want   image.Point
+	}{
+		{ShapeBox, image.Pt(0, 11), image.Pt(10, 11)},
+		{ShapeParallelogram, image.Pt(0, 11), image.Pt(11, 11)},
+		{ShapeParallelogram, image.Pt(40, 11), image.Pt(21, 11)},
+		{ShapeDiamond, image.Pt(16, 0), image.Pt(16, 10)},
+		{ShapeCircle, image.Pt(16, 30), image.Pt(16, 12)},
+	}
+	for _, tc := range tests {
+		if got := ShapeExit(r, tc.shape, tc.target); got != tc.want {
+			t.Errorf("shape %d toward %v: got %v, want %v", tc.shape, tc.target, got, tc.want)
+		}
+	}
+	// The top and bottom exits are on the outline of every shape
+	for s := ShapeBox; s <= ShapeCircle; s++ {
+		p := ShapeExit(r, s, image.Pt(16, 0))
+		if x0, x1, _ := ShapeSpan(r, s, p.Y); p.X < x0 || p.X > x1 {
+			t.Errorf("shape %d: top exit %v outside span [%d,%d]", s, p, x0, x1)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package drawutil
+
+// LineKind is the look of a line.
+type LineKind int
+
+const (
+	LineSolid  LineKind = iota // ─ │
+	LineDashed                 // ─ │ with gaps, see LineStyle.Dash
+	LineDotted                 // ┈ ┊
+	LineThick                  // ━ ┃
+	LineDouble                 // ═ ║
+)
+
+// LineStyle describes how a line is drawn. The zero value is a solid
+// light line.
+type LineStyle struct {
+	Kind LineKind
+
+	// Dash is the pattern of a LineDashed line: lengths in cells that
+	// are alternately drawn and skipped, repeating. Empty means
+	// DefaultDash.
+	Dash []int
+}
+
+// DefaultDash draws two cells and skips one.
+var DefaultDash = []int{2, 1}
+
+// drawn reports whether the i-th cell along a line in style s is drawn.
+func (s LineStyle) drawn(i int) bool {
+	if s.Kind != LineDashed {
+		return true
+	}
+	dash := s.Dash
+	period := 0
+	for _, n := range dash {
+		period += max(n, 0)
+	}
+	if period == 0 {
+		dash, period = DefaultDash, 3
+	}
+	i %= period
+	for k, n := range dash {
+		if i < n {
+			return k%2 == 0
+		}
+		i -= max(n, 0)
+	}
+	return true
+}
+
+// weight returns the set of box-drawing characters lines in style s
+// are drawn with.
+func (s LineStyle) weight() weight {
+	switch s.Kind {
+	case LineThick:
+		return weightHeavy
+	case LineDouble:
+		return weightDouble
+	}
+	return weightLight
+}
+
+// Arrow selects the arrowheads at the ends of a line.
+type Arrow int
+
+const (
+	ArrowTriangle Arrow = iota // ▲ ▼ ◄ ► at the end
+	ArrowOpen                  // ^ v < > at the end
+	ArrowNone                  // no arrowhead
+	ArrowBoth                  // ▲ ▼ ◄ ► at both ends
+)
+
+// Head returns the arrowhead for a line arriving in the dominant
+// direction of (dx, dy), or 0 for ArrowNone.
+func (a Arrow) Head(dx, dy int) rune {
+	switch a {
+	case ArrowNone:
+		return 0
+	case ArrowOpen:
+		if abs(dy) > abs(dx) {
+			if dy > 0 {
+				return 'v'
+			}
+			return '^'
+		}
+		if dx > 0 {
+			return '>'
+		}
+		return '<'
+	}
+	return ArrowChar(dx, dy)
+}
+
+// Stroke is how DrawStroke draws a path. The zero Stroke is a solid
+// line with a triangle arrowhead at the end that joins lines it
+// crosses.
+type Stroke struct {
+	Line     LineStyle
+	Arrow    Arrow
+	Crossing Crossing
+}
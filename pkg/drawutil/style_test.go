@@ -0,0 +1,109 @@
+package drawutil
+
+import (
+	"image"
+	"testing"
+
+	"github.com/wesen/grail/pkg/cellbuf"
+)
+
+// corner is an L-shaped path: right along row 0, then down column 4.
+var corner = []image.Point{{0, 0}, {4, 0}, {4, 3}}
+
+func drawCorner(s Stroke) *cellbuf.Buffer {
+	buf := cellbuf.New(6, 4, 0)
+	DrawStroke(buf, corner, s, 1, 2)
+	return buf
+}
+
+func TestDrawStrokeKinds(t *testing.T) {
+	tests := []struct {
+		kind           LineKind
+		h, elbow, v    rune
+		firstRow, last string
+	}{
+		{LineSolid, '─', '┐', '│', "────┐ ", "    ▼ "},
+		{LineDotted, '┈', '┐', '┊', "┈┈┈┈┐ ", "    ▼ "},
+		{LineThick, '━', '┓', '┃', "━━━━┓ ", "    ▼ "},
+		{LineDouble, '═', '╗', '║', "════╗ ", "    ▼ "},
+	}
+	for _, tc := range tests {
+		buf := drawCorner(Stroke{Line: LineStyle{Kind: tc.kind}})
+		if got := row(buf, 0); got != tc.firstRow {
+			t.Errorf("kind %d: row 0 = %q, want %q", tc.kind, got, tc.firstRow)
+		}
+		if got := buf.Cells[1][4].Ch; got != tc.v {
+			t.Errorf("kind %d: vertical = %c, want %c", tc.kind, got, tc.v)
+		}
+		if got := row(buf, 3); got != tc.last {
+			t.Errorf("kind %d: row 3 = %q, want %q", tc.kind, got, tc.last)
+		}
+	}
+}
+
+func TestLineStyleDash(t *testing.T) {
+	tests := []struct {
+		dash []int
+		want string
+	}{
+		{nil, "xx.xx.xx.x"},
+		{[]int{1, 1}, "x.x.x.x.x."},
+		{[]int{3, 2}, "xxx..xxx.."},
+		{[]int{0, 0}, "xx.xx.xx.x"}, // an empty pattern falls back to the default
+	}
+	for _, tc := range tests {
+		s := LineStyle{Kind: LineDashed, Dash: tc.dash}
+		got := make([]byte, len(tc.want))
+		for i := range got {
+			got[i] = '.'
+			if s.drawn(i) {
+				got[i] = 'x'
+			}
+		}
+		if string(got) != tc.want {
+			t.Errorf("dash %v: got %s, want %s", tc.dash, got, tc.want)
+		}
+	}
+	if !(LineStyle{Kind: LineThick, Dash: []int{1, 1}}).drawn(1) {
+		t.Error("only dashed lines have gaps")
+	}
+}
+
+func TestDrawStrokeArrows(t *testing.T) {
+	tests := []struct {
+		arrow      Arrow
+		start, end rune
+	}{
+		{ArrowTriangle, '─', '▼'},
+		{ArrowOpen, '─', 'v'},
+		{ArrowNone, '─', '│'},
+		{ArrowBoth, '◄', '▼'},
+	}
+	for _, tc := range tests {
+		buf := drawCorner(Stroke{Arrow: tc.arrow})
+		if got := buf.Cells[0][0].Ch; got != tc.start {
+			t.Errorf("arrow %d: start = %c, want %c", tc.arrow, got, tc.start)
+		}
+		if got := buf.Cells[3][4].Ch; got != tc.end {
+			t.Errorf("arrow %d: end = %c, want %c", tc.arrow, got, tc.end)
+		}
+	}
+}
+
+func TestDrawStrokeJunctionWeight(t *testing.T) {
+	// A thick line crossing a light one joins it with a heavy junction,
+	// and a light line crossing a double one with a light junction.
+	buf := cellbuf.New(5, 5, 0)
+	DrawStroke(buf, []image.Point{{2, 0}, {2, 4}}, Stroke{Arrow: ArrowNone}, 1, 1)
+	DrawStroke(buf, []image.Point{{0, 2}, {4, 2}}, Stroke{Line: LineStyle{Kind: LineThick}, Arrow: ArrowNone}, 1, 1)
+	if got := buf.Cells[2][2].Ch; got != '╋' {
+		t.Errorf("thick over light: got %c, want ╋", got)
+	}
+
+	buf = cellbuf.New(5, 5, 0)
+	DrawStroke(buf, []image.Point{{2, 0}, {2, 4}}, Stroke{Line: LineStyle{Kind: LineDouble}, Arrow: ArrowNone}, 1, 1)
+	DrawStroke(buf, []image.Point{{2, 2}, {4, 2}}, Stroke{Arrow: ArrowNone}, 1, 1)
+	if got := buf.Cells[2][2].Ch; got != '├' {
+		t.Errorf("light branch off double: got %c, want ├", got)
+	}
+}
@@ -0,0 +1,59 @@
+package graphmodel
+
+import "sort"
+
+// BackEdges returns the IDs of the edges that close a cycle: those that
+// lead back to a node still being visited by a depth-first search. The
+// search starts from the nodes without incoming edges, then from any
+// node not yet reached, each in ID order, and follows edges in insertion
+// order, so the result does not depend on z-order. Removing the back
+// edges leaves the graph acyclic; self-loops are always back edges.
+func (g *Graph[N, E]) BackEdges() map[int]bool {
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := make(map[int]int, len(g.nodes))
+	back := make(map[int]bool)
+
+	var visit func(id int)
+	visit = func(id int) {
+		state[id] = active
+		for _, e := range g.OutEdges(id) {
+			switch state[e.ToID] {
+			case active:
+				back[e.ID] = true
+			case unvisited:
+				if g.nodes[e.ToID] != nil {
+					visit(e.ToID)
+				}
+			}
+		}
+		state[id] = done
+	}
+
+	ids := make([]int, 0, len(g.nodes))
+	for id := range g.nodes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	hasIn := make(map[int]bool)
+	for _, e := range g.edges {
+		if e.FromID != e.ToID {
+			hasIn[e.ToID] = true
+		}
+	}
+	for _, id := range ids {
+		if !hasIn[id] && state[id] == unvisited {
+			visit(id)
+		}
+	}
+	for _, id := range ids {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+	return back
+}
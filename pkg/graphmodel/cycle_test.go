@@ -0,0 +1,49 @@
+package graphmodel
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestBackEdges(t *testing.T) {
+	g := New[testNode, string]()
+	start := g.AddNode(testNode{})
+	cond := g.AddNode(testNode{})
+	body := g.AddNode(testNode{})
+	join := g.AddNode(testNode{})
+	end := g.AddNode(testNode{})
+
+	g.AddEdge(start, cond, "")
+	g.AddEdge(cond, body, "Y")
+	g.AddEdge(body, join, "")
+	loop := g.AddEdge(join, cond, "")
+	g.AddEdge(cond, end, "N")
+	self := g.AddEdge(body, body, "")
+
+	want := map[int]bool{loop: true, self: true}
+	if got := g.BackEdges(); !maps.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// Raising a node must not change which edges are back edges
+	g.BringToFront(start)
+	g.SendToBack(join)
+	if got := g.BackEdges(); !maps.Equal(got, want) {
+		t.Errorf("after reordering: expected %v, got %v", want, got)
+	}
+}
+
+func TestBackEdgesWithoutSource(t *testing.T) {
+	g := New[testNode, string]()
+	a := g.AddNode(testNode{})
+	b := g.AddNode(testNode{})
+	g.AddEdge(a, b, "")
+	ba := g.AddEdge(b, a, "")
+
+	if got := g.BackEdges(); len(got) != 1 || !got[ba] {
+		t.Errorf("cycle with no source: expected only edge %d, got %v", ba, got)
+	}
+	if got := New[testNode, string]().BackEdges(); len(got) != 0 {
+		t.Errorf("empty graph: expected none, got %v", got)
+	}
+}
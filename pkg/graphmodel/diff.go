@@ -0,0 +1,181 @@
+package graphmodel
+
+import "sort"
+
+// Change is a set of flags describing how a node or edge differs between
+// two graphs.
+type Change uint8
+
+const (
+	Added    Change = 1 << iota // present only in the new graph
+	Removed                     // present only in the old graph
+	Moved                       // node position changed
+	Modified                    // node data (other than position) or edge data changed
+	Rewired                     // edge endpoints or ports changed
+)
+
+// Has reports whether all flags in f are set.
+func (c Change) Has(f Change) bool {
+	return c&f == f
+}
+
+// String returns the flags as a comma-separated list, e.g. "moved,modified".
+func (c Change) String() string {
+	names := []struct {
+		flag Change
+		name string
+	}{
+		{Added, "added"},
+		{Removed, "removed"},
+		{Moved, "moved"},
+		{Modified, "modified"},
+		{Rewired, "rewired"},
+	}
+	s := ""
+	for _, n := range names {
+		if c.Has(n.flag) {
+			if s != "" {
+				s += ","
+			}
+			s += n.name
+		}
+	}
+	if s == "" {
+		return "unchanged"
+	}
+	return s
+}
+
+// DiffOptions supplies the comparisons Diff and Merge3 cannot derive from
+// the generic types.
+type DiffOptions[N Spatial, E any] struct {
+	// NodeEqual reports whether two node values are equal, ignoring
+	// position (position changes are reported as Moved).
+	NodeEqual func(a, b N) bool
+	// EdgeEqual reports whether two edge data values are equal.
+	EdgeEqual func(a, b E) bool
+}
+
+// NodeDiff describes a changed node. Old or New is the zero value when
+// the node is Added or Removed respectively.
+type NodeDiff[N Spatial] struct {
+	ID       int
+	Change   Change
+	Old, New N
+}
+
+// EdgeDiff describes a changed edge. Old or New is the zero value when
+// the edge is Added or Removed respectively.
+type EdgeDiff[E any] struct {
+	ID       int
+	Change   Change
+	Old, New Edge[E]
+}
+
+// GraphDiff is the structural difference between two graphs, with nodes
+// and edges matched by ID. Only changed elements are listed, sorted by ID.
+type GraphDiff[N Spatial, E any] struct {
+	Nodes []NodeDiff[N]
+	Edges []EdgeDiff[E]
+}
+
+// Empty reports whether the graphs had no differences.
+func (d GraphDiff[N, E]) Empty() bool {
+	return len(d.Nodes) == 0 && len(d.Edges) == 0
+}
+
+// Diff compares two graphs, matching nodes and edges by ID. Z-order and
+// grouping are not compared.
+func Diff[N Spatial, E any](a, b *Graph[N, E], opt DiffOptions[N, E]) GraphDiff[N, E] {
+	var d GraphDiff[N, E]
+
+	for _, id := range unionIDs(a.nodes, b.nodes) {
+		na, nb := a.nodes[id], b.nodes[id]
+		var nd NodeDiff[N]
+		switch {
+		case na == nil:
+			nd = NodeDiff[N]{ID: id, Change: Added, New: nb.Data}
+		case nb == nil:
+			nd = NodeDiff[N]{ID: id, Change: Removed, Old: na.Data}
+		default:
+			nd = NodeDiff[N]{ID: id, Change: nodeChange(na.Data, nb.Data, opt), Old: na.Data, New: nb.Data}
+		}
+		if nd.Change != 0 {
+			d.Nodes = append(d.Nodes, nd)
+		}
+	}
+
+	ea, eb := edgeMap(a), edgeMap(b)
+	for _, id := range unionIDs(ea, eb) {
+		xa, inA := ea[id]
+		xb, inB := eb[id]
+		var ed EdgeDiff[E]
+		switch {
+		case !inA:
+			ed = EdgeDiff[E]{ID: id, Change: Added, New: xb}
+		case !inB:
+			ed = EdgeDiff[E]{ID: id, Change: Removed, Old: xa}
+		default:
+			ed = EdgeDiff[E]{ID: id, Change: edgeChange(xa, xb, opt), Old: xa, New: xb}
+		}
+		if ed.Change != 0 {
+			d.Edges = append(d.Edges, ed)
+		}
+	}
+	return d
+}
+
+// nodeChange compares two versions of the same node.
+func nodeChange[N Spatial, E any](a, b N, opt DiffOptions[N, E]) Change {
+	var c Change
+	if a.Pos() != b.Pos() {
+		c |= Moved
+	}
+	if !opt.NodeEqual(a, b) {
+		c |= Modified
+	}
+	return c
+}
+
+// edgeChange compares two versions of the same edge.
+func edgeChange[N Spatial, E any](a, b Edge[E], opt DiffOptions[N, E]) Change {
+	var c Change
+	if !sameWiring(a, b) {
+		c |= Rewired
+	}
+	if !opt.EdgeEqual(a.Data, b.Data) {
+		c |= Modified
+	}
+	return c
+}
+
+// sameWiring reports whether two edges have the same endpoints and ports.
+func sameWiring[E any](a, b Edge[E]) bool {
+	return a.FromID == b.FromID && a.ToID == b.ToID &&
+		a.FromPort == b.FromPort && a.ToPort == b.ToPort
+}
+
+// edgeMap indexes a graph's edges by ID.
+func edgeMap[N Spatial, E any](g *Graph[N, E]) map[int]Edge[E] {
+	m := make(map[int]Edge[E], len(g.edges))
+	for _, e := range g.edges {
+		m[e.ID] = e
+	}
+	return m
+}
+
+// unionIDs returns the sorted union of the keys of the given maps.
+func unionIDs[V any](maps ...map[int]V) []int {
+	seen := make(map[int]bool)
+	var ids []int
+	for _, m := range maps {
+		for id := range m {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
@@ -0,0 +1,105 @@
+package graphmodel
+
+import "testing"
+
+var testDiffOpts = DiffOptions[testNode, string]{
+	NodeEqual: func(a, b testNode) bool { return a.W == b.W && a.H == b.H },
+	EdgeEqual: func(a, b string) bool { return a == b },
+}
+
+// cloneGraph copies a graph through the Insert* API.
+func cloneGraph(g *Graph[testNode, string]) *Graph[testNode, string] {
+	c := New[testNode, string]()
+	for _, n := range g.Nodes() {
+		c.InsertNode(n.ID, n.Data)
+	}
+	for _, e := range g.Edges() {
+		c.InsertEdge(e)
+	}
+	return c
+}
+
+func TestDiffIdentical(t *testing.T) {
+	g := New[testNode, string]()
+	a := g.AddNode(testNode{X: 0, Y: 0, W: 5, H: 3})
+	b := g.AddNode(testNode{X: 10, Y: 0, W: 5, H: 3})
+	g.AddEdge(a, b, "x")
+
+	if d := Diff(g, cloneGraph(g), testDiffOpts); !d.Empty() {
+		t.Errorf("expected empty diff, got %+v", d)
+	}
+}
+
+func TestDiffNodes(t *testing.T) {
+	a := New[testNode, string]()
+	kept := a.AddNode(testNode{X: 0, Y: 0, W: 5, H: 3})
+	moved := a.AddNode(testNode{X: 10, Y: 0, W: 5, H: 3})
+	resized := a.AddNode(testNode{X: 20, Y: 0, W: 5, H: 3})
+	removed := a.AddNode(testNode{X: 30, Y: 0, W: 5, H: 3})
+
+	b := cloneGraph(a)
+	b.MoveNode(moved, testNode{X: 11, Y: 1}.Pos(), setPos)
+	b.Node(resized).Data.W = 9
+	b.RemoveNode(removed)
+	added := b.AddNode(testNode{X: 40, Y: 0, W: 5, H: 3})
+
+	d := Diff(a, b, testDiffOpts)
+	want := map[int]Change{
+		moved:   Moved,
+		resized: Modified,
+		removed: Removed,
+		added:   Added,
+	}
+	if len(d.Nodes) != len(want) {
+		t.Fatalf("expected %d node changes, got %+v", len(want), d.Nodes)
+	}
+	for _, nd := range d.Nodes {
+		if nd.ID == kept {
+			t.Errorf("unchanged node %d reported", kept)
+		}
+		if nd.Change != want[nd.ID] {
+			t.Errorf("node %d: expected %v, got %v", nd.ID, want[nd.ID], nd.Change)
+		}
+	}
+}
+
+func TestDiffEdges(t *testing.T) {
+	a := New[testNode, string]()
+	n0 := a.AddNode(testNode{X: 0, Y: 0, W: 5, H: 3})
+	n1 := a.AddNode(testNode{X: 10, Y: 0, W: 5, H: 3})
+	n2 := a.AddNode(testNode{X: 20, Y: 0, W: 5, H: 3})
+	relabeled := a.AddEdge(n0, n1, "Y")
+	rewired := a.AddEdge(n1, n2, "")
+	removed := a.AddEdge(n0, n2, "")
+
+	b := cloneGraph(a)
+	b.Edge(relabeled).Data = "N"
+	b.Edge(rewired).ToID = n0
+	b.RemoveEdgeByID(removed)
+	added := b.AddEdge(n2, n1, "")
+
+	d := Diff(a, b, testDiffOpts)
+	want := map[int]Change{
+		relabeled: Modified,
+		rewired:   Rewired,
+		removed:   Removed,
+		added:     Added,
+	}
+	if len(d.Edges) != len(want) {
+		t.Fatalf("expected %d edge changes, got %+v", len(want), d.Edges)
+	}
+	for _, ed := range d.Edges {
+		if ed.Change != want[ed.ID] {
+			t.Errorf("edge %d: expected %v, got %v", ed.ID, want[ed.ID], ed.Change)
+		}
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	if s := (Moved | Modified).String(); s != "moved,modified" {
+		t.Errorf("expected \"moved,modified\", got %q", s)
+	}
+	if s := Change(0).String(); s != "unchanged" {
+		t.Errorf("expected \"unchanged\", got %q", s)
+	}
+}
@@ -8,25 +8,44 @@ type Node[N Spatial] struct {
 	Data N
 }
 
-// Edge connects two nodes with a user-supplied label/data.
+// Edge connects two nodes with a user-supplied label/data. Edge IDs are
+// assigned by the graph and are independent of node IDs. FromPort and
+// ToPort optionally name the ports (see Ported) the edge attaches to; an
+// empty name leaves the attachment point to the renderer.
 type Edge[E any] struct {
-	FromID int
-	ToID   int
-	Data   E
+	ID       int
+	FromID   int
+	ToID     int
+	FromPort string
+	ToPort   string
+	Data     E
 }
 
-// Graph is a generic spatial graph with stable insertion-order iteration.
+// Graph is a generic spatial graph with stable z-order iteration. Nodes
+// are kept back-to-front; a new node is placed on top, and the order can
+// be changed with BringToFront, SendToBack, Raise and Lower.
 type Graph[N Spatial, E any] struct {
-	nodes    map[int]*Node[N]
-	edges    []Edge[E]
-	nextID   int
-	orderIDs []int // insertion order for deterministic iteration
+	nodes      map[int]*Node[N]
+	edges      []Edge[E]
+	nextID     int
+	nextEdgeID int
+	orderIDs   []int // z-order (back to front) for deterministic iteration
+	multi      bool  // allow parallel edges between the same pair
+
+	// Grouping (see group.go)
+	groups        map[int]*Group
+	groupOrder    []int // creation order
+	nextGroupID   int
+	parents       map[int]int // node ID → group ID; absent means top level
+	collapsedSize image.Point
 }
 
 // New creates an empty graph.
 func New[N Spatial, E any]() *Graph[N, E] {
 	return &Graph[N, E]{
-		nodes: make(map[int]*Node[N]),
+		nodes:   make(map[int]*Node[N]),
+		groups:  make(map[int]*Group),
+		parents: make(map[int]int),
 	}
 }
 
@@ -41,12 +60,28 @@ func (g *Graph[N, E]) AddNode(data N) int {
 	return id
 }
 
+// InsertNode adds a node with a caller-chosen ID, on top of the z-order.
+// It is used to restore saved graphs; later AddNode calls continue past
+// the highest ID seen. It returns false if the ID is negative or taken.
+func (g *Graph[N, E]) InsertNode(id int, data N) bool {
+	if _, ok := g.nodes[id]; ok || id < 0 {
+		return false
+	}
+	g.nodes[id] = &Node[N]{ID: id, Data: data}
+	g.orderIDs = append(g.orderIDs, id)
+	if id >= g.nextID {
+		g.nextID = id + 1
+	}
+	return true
+}
+
 // Node returns a pointer to the node with the given ID, or nil.
 func (g *Graph[N, E]) Node(id int) *Node[N] {
 	return g.nodes[id]
 }
 
-// Nodes returns all nodes in insertion order.
+// Nodes returns all nodes in z-order, back to front. Without explicit
+// reordering this is insertion order.
 func (g *Graph[N, E]) Nodes() []*Node[N] {
 	result := make([]*Node[N], 0, len(g.orderIDs))
 	for _, id := range g.orderIDs {
@@ -63,13 +98,11 @@ func (g *Graph[N, E]) RemoveNode(id int) {
 		return
 	}
 	delete(g.nodes, id)
+	delete(g.parents, id)
 
 	// Remove from orderIDs
-	for i, oid := range g.orderIDs {
-		if oid == id {
-			g.orderIDs = append(g.orderIDs[:i], g.orderIDs[i+1:]...)
-			break
-		}
+	if i := g.indexOf(id); i >= 0 {
+		g.orderIDs = append(g.orderIDs[:i], g.orderIDs[i+1:]...)
 	}
 
 	// Remove all connected edges
@@ -90,17 +123,140 @@ func (g *Graph[N, E]) MoveNode(id int, pos image.Point, setPos func(*N, image.Po
 	}
 }
 
+// ── Z-order ──
+
+// indexOf returns the position of id in the z-order, or -1.
+func (g *Graph[N, E]) indexOf(id int) int {
+	for i, oid := range g.orderIDs {
+		if oid == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// BringToFront moves the node to the top of the z-order.
+func (g *Graph[N, E]) BringToFront(id int) {
+	i := g.indexOf(id)
+	if i < 0 {
+		return
+	}
+	copy(g.orderIDs[i:], g.orderIDs[i+1:])
+	g.orderIDs[len(g.orderIDs)-1] = id
+}
+
+// SendToBack moves the node to the bottom of the z-order.
+func (g *Graph[N, E]) SendToBack(id int) {
+	i := g.indexOf(id)
+	if i < 0 {
+		return
+	}
+	copy(g.orderIDs[1:i+1], g.orderIDs[:i])
+	g.orderIDs[0] = id
+}
+
+// Raise moves the node one step up in the z-order.
+func (g *Graph[N, E]) Raise(id int) {
+	i := g.indexOf(id)
+	if i < 0 || i == len(g.orderIDs)-1 {
+		return
+	}
+	g.orderIDs[i], g.orderIDs[i+1] = g.orderIDs[i+1], g.orderIDs[i]
+}
+
+// Lower moves the node one step down in the z-order.
+func (g *Graph[N, E]) Lower(id int) {
+	i := g.indexOf(id)
+	if i <= 0 {
+		return
+	}
+	g.orderIDs[i], g.orderIDs[i-1] = g.orderIDs[i-1], g.orderIDs[i]
+}
+
 // ── Edge operations ──
 
-// AddEdge adds an edge between two nodes. Duplicate (fromID, toID) pairs
-// are silently ignored.
-func (g *Graph[N, E]) AddEdge(fromID, toID int, data E) {
+// SetMultiEdges enables or disables multigraph mode. When enabled,
+// AddEdge accepts any number of parallel edges between the same pair of
+// nodes. It is disabled by default.
+func (g *Graph[N, E]) SetMultiEdges(on bool) {
+	g.multi = on
+}
+
+// MultiEdges reports whether multigraph mode is enabled.
+func (g *Graph[N, E]) MultiEdges() bool {
+	return g.multi
+}
+
+// AddEdge adds an edge between two nodes and returns its assigned ID.
+// Unless multigraph mode is enabled, a duplicate (fromID, toID) pair is
+// ignored and the ID of the existing edge is returned.
+func (g *Graph[N, E]) AddEdge(fromID, toID int, data E) int {
+	return g.AddPortEdge(fromID, "", toID, "", data)
+}
+
+// AddPortEdge is like AddEdge but attaches the edge to named ports on
+// its source and target nodes.
+func (g *Graph[N, E]) AddPortEdge(fromID int, fromPort string, toID int, toPort string, data E) int {
+	if !g.multi {
+		for _, e := range g.edges {
+			if e.FromID == fromID && e.ToID == toID {
+				return e.ID
+			}
+		}
+	}
+	id := g.nextEdgeID
+	g.nextEdgeID++
+	g.edges = append(g.edges, Edge[E]{
+		ID: id, FromID: fromID, ToID: toID,
+		FromPort: fromPort, ToPort: toPort,
+		Data: data,
+	})
+	return id
+}
+
+// InsertEdge adds an edge with its ID, endpoints and ports as given. Like
+// InsertNode it is used to restore saved graphs. It returns false if the
+// ID is negative or taken; duplicate pairs are not checked.
+func (g *Graph[N, E]) InsertEdge(e Edge[E]) bool {
+	if e.ID < 0 || g.Edge(e.ID) != nil {
+		return false
+	}
+	g.edges = append(g.edges, e)
+	if e.ID >= g.nextEdgeID {
+		g.nextEdgeID = e.ID + 1
+	}
+	return true
+}
+
+// Edge returns a pointer to the edge with the given ID, or nil. The
+// pointer is only valid until the next edge insertion or removal.
+func (g *Graph[N, E]) Edge(id int) *Edge[E] {
+	for i := range g.edges {
+		if g.edges[i].ID == id {
+			return &g.edges[i]
+		}
+	}
+	return nil
+}
+
+// HasEdge reports whether at least one edge runs from fromID to toID.
+func (g *Graph[N, E]) HasEdge(fromID, toID int) bool {
 	for _, e := range g.edges {
 		if e.FromID == fromID && e.ToID == toID {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveEdgeByID removes the edge with the given ID.
+func (g *Graph[N, E]) RemoveEdgeByID(id int) {
+	for i, e := range g.edges {
+		if e.ID == id {
+			g.edges = append(g.edges[:i], g.edges[i+1:]...)
 			return
 		}
 	}
-	g.edges = append(g.edges, Edge[E]{FromID: fromID, ToID: toID, Data: data})
 }
 
 // RemoveEdge removes the first edge matching (fromID, toID).
@@ -140,14 +296,35 @@ func (g *Graph[N, E]) InEdges(toID int) []Edge[E] {
 	return result
 }
 
+// ParallelIndex returns the position of the edge among all edges that
+// connect the same unordered pair of nodes, and the size of that group.
+// A non-parallel edge yields (0, 1); an unknown ID yields (0, 0).
+func (g *Graph[N, E]) ParallelIndex(id int) (index, count int) {
+	target := g.Edge(id)
+	if target == nil {
+		return 0, 0
+	}
+	a, b := target.FromID, target.ToID
+	for _, e := range g.edges {
+		if (e.FromID == a && e.ToID == b) || (e.FromID == b && e.ToID == a) {
+			if e.ID == id {
+				index = count
+			}
+			count++
+		}
+	}
+	return index, count
+}
+
 // ── Spatial queries ──
 
-// HitTest returns the topmost (last-inserted) node containing the point,
-// or nil if no node contains it.
+// HitTest returns the topmost node (last in z-order) containing the
+// point, or nil if no node contains it. Nodes hidden inside a collapsed
+// group are skipped.
 func (g *Graph[N, E]) HitTest(pt image.Point) *Node[N] {
 	for i := len(g.orderIDs) - 1; i >= 0; i-- {
 		n := g.nodes[g.orderIDs[i]]
-		if n != nil && pt.In(BoundsOf(n.Data)) {
+		if n != nil && pt.In(BoundsOf(n.Data)) && !g.Hidden(n.ID) {
 			return n
 		}
 	}
@@ -155,7 +332,7 @@ func (g *Graph[N, E]) HitTest(pt image.Point) *Node[N] {
 }
 
 // NodesInRect returns all nodes whose bounds intersect the given rectangle,
-// in insertion order.
+// in z-order.
 func (g *Graph[N, E]) NodesInRect(r image.Rectangle) []*Node[N] {
 	var result []*Node[N]
 	for _, id := range g.orderIDs {
@@ -168,6 +168,122 @@ func TestAddEdgeDuplicate(t *testing.T) {
 	}
 }
 
+func TestAddEdgeReturnsIDs(t *testing.T) {
+	g := New[testNode, string]()
+	a := g.AddNode(testNode{X: 0, Y: 0, W: 5, H: 3})
+	b := g.AddNode(testNode{X: 10, Y: 0, W: 5, H: 3})
+	c := g.AddNode(testNode{X: 20, Y: 0, W: 5, H: 3})
+	e0 := g.AddEdge(a, b, "a→b")
+	e1 := g.AddEdge(b, c, "b→c")
+	if e0 != 0 || e1 != 1 {
+		t.Errorf("expected edge IDs 0,1, got %d,%d", e0, e1)
+	}
+	// Duplicate returns the existing edge's ID
+	if dup := g.AddEdge(a, b, "again"); dup != e0 {
+		t.Errorf("duplicate AddEdge: expected ID %d, got %d", e0, dup)
+	}
+}
+
+func TestMultiEdges(t *testing.T) {
+	g := New[testNode, string]()
+	g.SetMultiEdges(true)
+	a := g.AddNode(testNode{X: 0, Y: 0, W: 5, H: 3})
+	b := g.AddNode(testNode{X: 10, Y: 0, W: 5, H: 3})
+	y := g.AddEdge(a, b, "Y")
+	n := g.AddEdge(a, b, "N")
+	if y == n {
+		t.Fatalf("parallel edges should get distinct IDs, both got %d", y)
+	}
+	if len(g.Edges()) != 2 {
+		t.Fatalf("expected 2 parallel edges, got %d", len(g.Edges()))
+	}
+
+	// RemoveEdge by pair removes only the first
+	g.RemoveEdge(a, b)
+	edges := g.Edges()
+	if len(edges) != 1 || edges[0].ID != n {
+		t.Errorf("expected only edge %d to remain, got %v", n, edges)
+	}
+}
+
+func TestEdgeByID(t *testing.T) {
+	g := New[testNode, string]()
+	a := g.AddNode(testNode{X: 0, Y: 0, W: 5, H: 3})
+	b := g.AddNode(testNode{X: 10, Y: 0, W: 5, H: 3})
+	id := g.AddEdge(a, b, "hello")
+	e := g.Edge(id)
+	if e == nil {
+		t.Fatal("Edge() returned nil")
+	}
+	if e.FromID != a || e.ToID != b || e.Data != "hello" {
+		t.Errorf("Edge(%d): unexpected %+v", id, *e)
+	}
+	if g.Edge(999) != nil {
+		t.Error("expected nil for non-existent edge ID")
+	}
+}
+
+func TestRemoveEdgeByID(t *testing.T) {
+	g := New[testNode, string]()
+	g.SetMultiEdges(true)
+	a := g.AddNode(testNode{X: 0, Y: 0, W: 5, H: 3})
+	b := g.AddNode(testNode{X: 10, Y: 0, W: 5, H: 3})
+	first := g.AddEdge(a, b, "Y")
+	second := g.AddEdge(a, b, "N")
+	g.RemoveEdgeByID(second)
+	if g.Edge(second) != nil {
+		t.Error("edge should be gone after RemoveEdgeByID")
+	}
+	if g.Edge(first) == nil {
+		t.Error("RemoveEdgeByID removed the wrong edge")
+	}
+	// IDs are not reused
+	if third := g.AddEdge(a, b, "again"); third == first || third == second {
+		t.Errorf("edge ID %d was reused", third)
+	}
+}
+
+func TestHasEdge(t *testing.T) {
+	g := New[testNode, string]()
+	a := g.AddNode(testNode{X: 0, Y: 0, W: 5, H: 3})
+	b := g.AddNode(testNode{X: 10, Y: 0, W: 5, H: 3})
+	g.AddEdge(a, b, "")
+	if !g.HasEdge(a, b) {
+		t.Error("HasEdge(a, b) should be true")
+	}
+	if g.HasEdge(b, a) {
+		t.Error("HasEdge(b, a) should be false (edges are directed)")
+	}
+}
+
+func TestParallelIndex(t *testing.T) {
+	g := New[testNode, string]()
+	g.SetMultiEdges(true)
+	a := g.AddNode(testNode{X: 0, Y: 0, W: 5, H: 3})
+	b := g.AddNode(testNode{X: 10, Y: 0, W: 5, H: 3})
+	c := g.AddNode(testNode{X: 20, Y: 0, W: 5, H: 3})
+	e0 := g.AddEdge(a, b, "Y")
+	e1 := g.AddEdge(a, c, "")
+	e2 := g.AddEdge(b, a, "back")
+	e3 := g.AddEdge(a, b, "N")
+
+	tests := []struct {
+		id, index, count int
+	}{
+		{e0, 0, 3},
+		{e2, 1, 3},
+		{e3, 2, 3},
+		{e1, 0, 1},
+		{999, 0, 0},
+	}
+	for _, tc := range tests {
+		i, n := g.ParallelIndex(tc.id)
+		if i != tc.index || n != tc.count {
+			t.Errorf("ParallelIndex(%d) = (%d,%d), want (%d,%d)", tc.id, i, n, tc.index, tc.count)
+		}
+	}
+}
+
 func TestRemoveEdge(t *testing.T) {
 	g := New[testNode, string]()
 	a := g.AddNode(testNode{X: 0, Y: 0, W: 5, H: 3})
@@ -249,6 +365,97 @@ func TestHitTestEmptyGraph(t *testing.T) {
 	}
 }
 
+// ── Z-order ──
+
+// orderOf returns node IDs in z-order.
+func orderOf(g *Graph[testNode, string]) []int {
+	var ids []int
+	for _, n := range g.Nodes() {
+		ids = append(ids, n.ID)
+	}
+	return ids
+}
+
+func equalIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBringToFront(t *testing.T) {
+	g := New[testNode, string]()
+	a := g.AddNode(testNode{W: 5, H: 3})
+	b := g.AddNode(testNode{W: 5, H: 3})
+	c := g.AddNode(testNode{W: 5, H: 3})
+	g.BringToFront(a)
+	if got := orderOf(g); !equalIDs(got, []int{b, c, a}) {
+		t.Errorf("BringToFront: expected [%d %d %d], got %v", b, c, a, got)
+	}
+}
+
+func TestSendToBack(t *testing.T) {
+	g := New[testNode, string]()
+	a := g.AddNode(testNode{W: 5, H: 3})
+	b := g.AddNode(testNode{W: 5, H: 3})
+	c := g.AddNode(testNode{W: 5, H: 3})
+	g.SendToBack(c)
+	if got := orderOf(g); !equalIDs(got, []int{c, a, b}) {
+		t.Errorf("SendToBack: expected [%d %d %d], got %v", c, a, b, got)
+	}
+}
+
+func TestRaiseLower(t *testing.T) {
+	g := New[testNode, string]()
+	a := g.AddNode(testNode{W: 5, H: 3})
+	b := g.AddNode(testNode{W: 5, H: 3})
+	c := g.AddNode(testNode{W: 5, H: 3})
+
+	g.Raise(a)
+	if got := orderOf(g); !equalIDs(got, []int{b, a, c}) {
+		t.Errorf("Raise: expected [%d %d %d], got %v", b, a, c, got)
+	}
+	g.Lower(c)
+	if got := orderOf(g); !equalIDs(got, []int{b, c, a}) {
+		t.Errorf("Lower: expected [%d %d %d], got %v", b, c, a, got)
+	}
+
+	// Raising the top node or lowering the bottom node is a no-op
+	g.Raise(a)
+	g.Lower(b)
+	if got := orderOf(g); !equalIDs(got, []int{b, c, a}) {
+		t.Errorf("no-op Raise/Lower changed order: %v", got)
+	}
+}
+
+func TestZOrderNonExistent(t *testing.T) {
+	g := New[testNode, string]()
+	a := g.AddNode(testNode{W: 5, H: 3})
+	g.BringToFront(999)
+	g.SendToBack(999)
+	g.Raise(999)
+	g.Lower(999)
+	if got := orderOf(g); !equalIDs(got, []int{a}) {
+		t.Errorf("z-order ops on missing ID changed order: %v", got)
+	}
+}
+
+func TestHitTestFollowsZOrder(t *testing.T) {
+	g := New[testNode, string]()
+	bottom := g.AddNode(testNode{X: 10, Y: 10, W: 10, H: 10})
+	g.AddNode(testNode{X: 12, Y: 12, W: 10, H: 10})
+	g.BringToFront(bottom)
+	hit := g.HitTest(image.Pt(15, 15))
+	if hit == nil || hit.ID != bottom {
+		t.Errorf("expected raised node %d on top, got %v", bottom, hit)
+	}
+}
+
 // ── NodesInRect ──
 
 func TestNodesInRect(t *testing.T) {
@@ -0,0 +1,356 @@
+package graphmodel
+
+import "image"
+
+// NoGroup is the parent ID of top-level nodes and groups.
+const NoGroup = -1
+
+// GroupPadding is the margin, in cells, between a group's children and
+// its computed frame.
+const GroupPadding = 1
+
+// Group is a container that visually encloses nodes and other groups.
+// Group IDs are assigned by the graph and are independent of node IDs.
+type Group struct {
+	ID       int
+	ParentID int // enclosing group, or NoGroup
+	Label    string
+
+	// Bounds overrides the computed bounds when non-empty. When empty,
+	// the bounds are the union of the children's bounds plus GroupPadding.
+	Bounds image.Rectangle
+
+	// Collapsed groups hide their descendants and are drawn as a single
+	// placeholder (see SetCollapsedSize).
+	Collapsed bool
+}
+
+// HitKind classifies the result of HitTestDeep.
+type HitKind int
+
+const (
+	HitNone       HitKind = iota
+	HitNode               // a visible node
+	HitGroupFrame         // the border of a group, or a collapsed placeholder
+	HitGroup              // inside a group, but not on a node or its frame
+)
+
+// Hit is the result of HitTestDeep. Group is the innermost visible group
+// containing the point, for every kind except HitNone.
+type Hit[N Spatial] struct {
+	Kind  HitKind
+	Node  *Node[N]
+	Group *Group
+}
+
+// ── Group operations ──
+
+// AddGroup creates an empty group inside parentID (or NoGroup) and
+// returns its assigned ID. An unknown parent is treated as NoGroup.
+func (g *Graph[N, E]) AddGroup(label string, parentID int) int {
+	if _, ok := g.groups[parentID]; !ok {
+		parentID = NoGroup
+	}
+	id := g.nextGroupID
+	g.nextGroupID++
+	g.groups[id] = &Group{ID: id, ParentID: parentID, Label: label}
+	g.groupOrder = append(g.groupOrder, id)
+	return id
+}
+
+// InsertGroup adds a copy of grp with its ID as given. Like InsertNode it
+// is used to restore saved graphs; insert parents before their children.
+// It returns false if the ID is negative or taken. An unknown parent is
+// treated as NoGroup.
+func (g *Graph[N, E]) InsertGroup(grp Group) bool {
+	if _, ok := g.groups[grp.ID]; ok || grp.ID < 0 {
+		return false
+	}
+	if _, ok := g.groups[grp.ParentID]; !ok {
+		grp.ParentID = NoGroup
+	}
+	g.groups[grp.ID] = &grp
+	g.groupOrder = append(g.groupOrder, grp.ID)
+	if grp.ID >= g.nextGroupID {
+		g.nextGroupID = grp.ID + 1
+	}
+	return true
+}
+
+// Group returns a pointer to the group with the given ID, or nil.
+func (g *Graph[N, E]) Group(id int) *Group {
+	return g.groups[id]
+}
+
+// Groups returns all groups, outermost first. Groups at the same depth
+// keep their creation order, so drawing in this order puts inner frames
+// on top of outer ones.
+func (g *Graph[N, E]) Groups() []*Group {
+	result := make([]*Group, 0, len(g.groupOrder))
+	for depth := 0; len(result) < len(g.groupOrder); depth++ {
+		for _, id := range g.groupOrder {
+			if g.groupDepth(id) == depth {
+				result = append(result, g.groups[id])
+			}
+		}
+	}
+	return result
+}
+
+// groupDepth returns the nesting depth of a group (0 for top level).
+func (g *Graph[N, E]) groupDepth(id int) int {
+	depth := 0
+	for p := g.groups[id].ParentID; p != NoGroup; p = g.groups[p].ParentID {
+		depth++
+	}
+	return depth
+}
+
+// RemoveGroup deletes the group. Its child nodes and groups move up to
+// the removed group's parent.
+func (g *Graph[N, E]) RemoveGroup(id int) {
+	grp, ok := g.groups[id]
+	if !ok {
+		return
+	}
+	for nodeID, p := range g.parents {
+		if p == id {
+			g.setParent(nodeID, grp.ParentID)
+		}
+	}
+	for _, other := range g.groups {
+		if other.ParentID == id {
+			other.ParentID = grp.ParentID
+		}
+	}
+	delete(g.groups, id)
+	for i, gid := range g.groupOrder {
+		if gid == id {
+			g.groupOrder = append(g.groupOrder[:i], g.groupOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+// SetParent places a node inside a group. Passing NoGroup makes the node
+// top level. Unknown nodes or groups are ignored.
+func (g *Graph[N, E]) SetParent(nodeID, groupID int) {
+	if _, ok := g.nodes[nodeID]; !ok {
+		return
+	}
+	if _, ok := g.groups[groupID]; !ok && groupID != NoGroup {
+		return
+	}
+	g.setParent(nodeID, groupID)
+}
+
+func (g *Graph[N, E]) setParent(nodeID, groupID int) {
+	if groupID == NoGroup {
+		delete(g.parents, nodeID)
+		return
+	}
+	g.parents[nodeID] = groupID
+}
+
+// SetGroupParent nests a group inside another group (or NoGroup). It
+// returns false, leaving the hierarchy unchanged, if either group is
+// unknown or the move would create a cycle.
+func (g *Graph[N, E]) SetGroupParent(groupID, parentID int) bool {
+	grp, ok := g.groups[groupID]
+	if !ok {
+		return false
+	}
+	if parentID != NoGroup {
+		if _, ok := g.groups[parentID]; !ok {
+			return false
+		}
+		for p := parentID; p != NoGroup; p = g.groups[p].ParentID {
+			if p == groupID {
+				return false
+			}
+		}
+	}
+	grp.ParentID = parentID
+	return true
+}
+
+// ParentOf returns the group directly containing the node, or NoGroup.
+func (g *Graph[N, E]) ParentOf(nodeID int) int {
+	if p, ok := g.parents[nodeID]; ok {
+		return p
+	}
+	return NoGroup
+}
+
+// ChildNodes returns the nodes directly inside the group, in z-order.
+func (g *Graph[N, E]) ChildNodes(groupID int) []*Node[N] {
+	var result []*Node[N]
+	for _, id := range g.orderIDs {
+		if p, ok := g.parents[id]; ok && p == groupID {
+			result = append(result, g.nodes[id])
+		}
+	}
+	return result
+}
+
+// ChildGroups returns the groups directly inside the group, in creation
+// order.
+func (g *Graph[N, E]) ChildGroups(groupID int) []*Group {
+	var result []*Group
+	for _, id := range g.groupOrder {
+		if g.groups[id].ParentID == groupID {
+			result = append(result, g.groups[id])
+		}
+	}
+	return result
+}
+
+// InGroup reports whether the node is inside the group, directly or
+// through nested groups.
+func (g *Graph[N, E]) InGroup(nodeID, groupID int) bool {
+	for p := g.ParentOf(nodeID); p != NoGroup; p = g.groups[p].ParentID {
+		if p == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+// ── Collapsing ──
+
+// SetCollapsedSize sets the size of the placeholder that GroupBounds
+// reports for a collapsed group. The placeholder sits at the top-left of
+// the expanded bounds. A zero size (the default) keeps the full bounds.
+func (g *Graph[N, E]) SetCollapsedSize(sz image.Point) {
+	g.collapsedSize = sz
+}
+
+// CollapsedAncestor returns the outermost collapsed group containing the
+// node, or NoGroup if the node is visible.
+func (g *Graph[N, E]) CollapsedAncestor(nodeID int) int {
+	return g.collapsedAbove(g.ParentOf(nodeID))
+}
+
+// collapsedAbove returns the outermost collapsed group among groupID and
+// its ancestors, or NoGroup.
+func (g *Graph[N, E]) collapsedAbove(groupID int) int {
+	found := NoGroup
+	for p := groupID; p != NoGroup; p = g.groups[p].ParentID {
+		if g.groups[p].Collapsed {
+			found = p
+		}
+	}
+	return found
+}
+
+// Hidden reports whether the node is hidden by a collapsed ancestor.
+func (g *Graph[N, E]) Hidden(nodeID int) bool {
+	return g.CollapsedAncestor(nodeID) != NoGroup
+}
+
+// GroupHidden reports whether the group is hidden by a collapsed
+// ancestor. A collapsed group itself is not hidden; it shows as a
+// placeholder.
+func (g *Graph[N, E]) GroupHidden(groupID int) bool {
+	grp, ok := g.groups[groupID]
+	if !ok {
+		return false
+	}
+	return g.collapsedAbove(grp.ParentID) != NoGroup
+}
+
+// ── Group geometry ──
+
+// GroupBounds returns the frame rectangle of a group: its explicit
+// Bounds if set, otherwise the union of its children's bounds expanded
+// by GroupPadding. A collapsed group reports its placeholder rectangle.
+// An empty group without explicit bounds has empty bounds.
+func (g *Graph[N, E]) GroupBounds(id int) image.Rectangle {
+	grp, ok := g.groups[id]
+	if !ok {
+		return image.Rectangle{}
+	}
+	r := g.expandedBounds(grp)
+	if grp.Collapsed && g.collapsedSize != (image.Point{}) && !r.Empty() {
+		return image.Rectangle{Min: r.Min, Max: r.Min.Add(g.collapsedSize)}
+	}
+	return r
+}
+
+func (g *Graph[N, E]) expandedBounds(grp *Group) image.Rectangle {
+	if !grp.Bounds.Empty() {
+		return grp.Bounds
+	}
+	var r image.Rectangle
+	for _, n := range g.ChildNodes(grp.ID) {
+		r = r.Union(BoundsOf(n.Data))
+	}
+	for _, child := range g.ChildGroups(grp.ID) {
+		r = r.Union(g.GroupBounds(child.ID))
+	}
+	if r.Empty() {
+		return r
+	}
+	return r.Inset(-GroupPadding)
+}
+
+// MoveGroup translates every node inside the group (at any depth) by
+// delta, along with the explicit bounds of the group and its
+// descendants. setPos has the same role as in MoveNode.
+func (g *Graph[N, E]) MoveGroup(id int, delta image.Point, setPos func(*N, image.Point)) {
+	if _, ok := g.groups[id]; !ok || delta == (image.Point{}) {
+		return
+	}
+	for nodeID := range g.parents {
+		if g.InGroup(nodeID, id) {
+			n := g.nodes[nodeID]
+			setPos(&n.Data, n.Data.Pos().Add(delta))
+		}
+	}
+	for _, grp := range g.groups {
+		if (grp.ID == id || g.groupInside(grp.ID, id)) && !grp.Bounds.Empty() {
+			grp.Bounds = grp.Bounds.Add(delta)
+		}
+	}
+}
+
+// groupInside reports whether groupID is nested (at any depth) in
+// ancestorID.
+func (g *Graph[N, E]) groupInside(groupID, ancestorID int) bool {
+	for p := g.groups[groupID].ParentID; p != NoGroup; p = g.groups[p].ParentID {
+		if p == ancestorID {
+			return true
+		}
+	}
+	return false
+}
+
+// HitTestDeep is like HitTest but also reports groups. Visible nodes take
+// priority; otherwise the innermost visible group containing the point is
+// reported as HitGroupFrame if the point lies on its border (or anywhere
+// on a collapsed placeholder) and HitGroup if it lies inside.
+func (g *Graph[N, E]) HitTestDeep(pt image.Point) Hit[N] {
+	var inner *Group
+	innerDepth := -1
+	for _, id := range g.groupOrder {
+		if g.GroupHidden(id) || !pt.In(g.GroupBounds(id)) {
+			continue
+		}
+		if d := g.groupDepth(id); d > innerDepth {
+			inner, innerDepth = g.groups[id], d
+		}
+	}
+
+	if n := g.HitTest(pt); n != nil {
+		return Hit[N]{Kind: HitNode, Node: n, Group: inner}
+	}
+	if inner == nil {
+		return Hit[N]{}
+	}
+	r := g.GroupBounds(inner.ID)
+	onFrame := pt.X == r.Min.X || pt.X == r.Max.X-1 || pt.Y == r.Min.Y || pt.Y == r.Max.Y-1
+	if inner.Collapsed || onFrame {
+		return Hit[N]{Kind: HitGroupFrame, Group: inner}
+	}
+	return Hit[N]{Kind: HitGroup, Group: inner}
+}
@@ -0,0 +1,295 @@
+package graphmodel
+
+import (
+	"image"
+	"testing"
+)
+
+// ── Hierarchy ──
+
+func TestAddGroup(t *testing.T) {
+	g := New[testNode, string]()
+	outer := g.AddGroup("outer", NoGroup)
+	inner := g.AddGroup("inner", outer)
+	if g.Group(outer) == nil || g.Group(inner) == nil {
+		t.Fatal("Group() returned nil")
+	}
+	if g.Group(inner).ParentID != outer {
+		t.Errorf("inner parent: expected %d, got %d", outer, g.Group(inner).ParentID)
+	}
+	if g.Group(outer).ParentID != NoGroup {
+		t.Errorf("outer parent: expected NoGroup, got %d", g.Group(outer).ParentID)
+	}
+	// Unknown parent falls back to top level
+	orphan := g.AddGroup("orphan", 999)
+	if g.Group(orphan).ParentID != NoGroup {
+		t.Errorf("unknown parent: expected NoGroup, got %d", g.Group(orphan).ParentID)
+	}
+}
+
+func TestGroupsOutermostFirst(t *testing.T) {
+	g := New[testNode, string]()
+	a := g.AddGroup("a", NoGroup)
+	inner := g.AddGroup("inner", a)
+	b := g.AddGroup("b", NoGroup)
+
+	groups := g.Groups()
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %d", len(groups))
+	}
+	if groups[0].ID != a || groups[1].ID != b || groups[2].ID != inner {
+		t.Errorf("expected order [%d %d %d], got [%d %d %d]",
+			a, b, inner, groups[0].ID, groups[1].ID, groups[2].ID)
+	}
+}
+
+func TestSetParent(t *testing.T) {
+	g := New[testNode, string]()
+	grp := g.AddGroup("g", NoGroup)
+	n := g.AddNode(testNode{X: 0, Y: 0, W: 5, H: 3})
+
+	g.SetParent(n, grp)
+	if g.ParentOf(n) != grp {
+		t.Errorf("ParentOf: expected %d, got %d", grp, g.ParentOf(n))
+	}
+	if len(g.ChildNodes(grp)) != 1 {
+		t.Errorf("ChildNodes: expected 1, got %d", len(g.ChildNodes(grp)))
+	}
+
+	g.SetParent(n, 999) // unknown group, ignored
+	if g.ParentOf(n) != grp {
+		t.Error("SetParent with unknown group should be ignored")
+	}
+
+	g.SetParent(n, NoGroup)
+	if g.ParentOf(n) != NoGroup {
+		t.Error("SetParent(NoGroup) should make the node top level")
+	}
+}
+
+func TestSetGroupParentRejectsCycle(t *testing.T) {
+	g := New[testNode, string]()
+	a := g.AddGroup("a", NoGroup)
+	b := g.AddGroup("b", a)
+	if g.SetGroupParent(a, b) {
+		t.Error("nesting a group inside its own child should fail")
+	}
+	if g.SetGroupParent(a, a) {
+		t.Error("nesting a group inside itself should fail")
+	}
+	if g.Group(a).ParentID != NoGroup {
+		t.Error("failed SetGroupParent changed the hierarchy")
+	}
+}
+
+func TestInGroupNested(t *testing.T) {
+	g := New[testNode, string]()
+	outer := g.AddGroup("outer", NoGroup)
+	inner := g.AddGroup("inner", outer)
+	n := g.AddNode(testNode{X: 0, Y: 0, W: 5, H: 3})
+	g.SetParent(n, inner)
+	if !g.InGroup(n, inner) || !g.InGroup(n, outer) {
+		t.Error("node should be in both inner and outer groups")
+	}
+}
+
+func TestRemoveGroupReparentsChildren(t *testing.T) {
+	g := New[testNode, string]()
+	outer := g.AddGroup("outer", NoGroup)
+	mid := g.AddGroup("mid", outer)
+	inner := g.AddGroup("inner", mid)
+	n := g.AddNode(testNode{X: 0, Y: 0, W: 5, H: 3})
+	g.SetParent(n, mid)
+
+	g.RemoveGroup(mid)
+	if g.Group(mid) != nil {
+		t.Fatal("group should be gone after RemoveGroup")
+	}
+	if g.ParentOf(n) != outer {
+		t.Errorf("node parent: expected %d, got %d", outer, g.ParentOf(n))
+	}
+	if g.Group(inner).ParentID != outer {
+		t.Errorf("inner group parent: expected %d, got %d", outer, g.Group(inner).ParentID)
+	}
+}
+
+func TestRemoveNodeClearsParent(t *testing.T) {
+	g := New[testNode, string]()
+	grp := g.AddGroup("g", NoGroup)
+	n := g.AddNode(testNode{X: 0, Y: 0, W: 5, H: 3})
+	g.SetParent(n, grp)
+	g.RemoveNode(n)
+	if len(g.ChildNodes(grp)) != 0 {
+		t.Error("removed node should no longer be a child")
+	}
+}
+
+// ── Geometry ──
+
+func TestGroupBoundsComputed(t *testing.T) {
+	g := New[testNode, string]()
+	grp := g.AddGroup("g", NoGroup)
+	a := g.AddNode(testNode{X: 10, Y: 10, W: 5, H: 3})
+	b := g.AddNode(testNode{X: 20, Y: 16, W: 5, H: 3})
+	g.SetParent(a, grp)
+	g.SetParent(b, grp)
+
+	want := image.Rect(10, 10, 25, 19).Inset(-GroupPadding)
+	if got := g.GroupBounds(grp); got != want {
+		t.Errorf("GroupBounds: expected %v, got %v", want, got)
+	}
+}
+
+func TestGroupBoundsNested(t *testing.T) {
+	g := New[testNode, string]()
+	outer := g.AddGroup("outer", NoGroup)
+	inner := g.AddGroup("inner", outer)
+	n := g.AddNode(testNode{X: 10, Y: 10, W: 5, H: 3})
+	g.SetParent(n, inner)
+
+	innerWant := image.Rect(10, 10, 15, 13).Inset(-GroupPadding)
+	outerWant := innerWant.Inset(-GroupPadding)
+	if got := g.GroupBounds(inner); got != innerWant {
+		t.Errorf("inner bounds: expected %v, got %v", innerWant, got)
+	}
+	if got := g.GroupBounds(outer); got != outerWant {
+		t.Errorf("outer bounds: expected %v, got %v", outerWant, got)
+	}
+}
+
+func TestGroupBoundsExplicit(t *testing.T) {
+	g := New[testNode, string]()
+	grp := g.AddGroup("g", NoGroup)
+	n := g.AddNode(testNode{X: 10, Y: 10, W: 5, H: 3})
+	g.SetParent(n, grp)
+	g.Group(grp).Bounds = image.Rect(0, 0, 40, 20)
+	if got := g.GroupBounds(grp); got != image.Rect(0, 0, 40, 20) {
+		t.Errorf("explicit bounds: expected (0,0)-(40,20), got %v", got)
+	}
+}
+
+func TestGroupBoundsEmpty(t *testing.T) {
+	g := New[testNode, string]()
+	grp := g.AddGroup("g", NoGroup)
+	if !g.GroupBounds(grp).Empty() {
+		t.Error("empty group should have empty bounds")
+	}
+}
+
+func TestMoveGroup(t *testing.T) {
+	g := New[testNode, string]()
+	outer := g.AddGroup("outer", NoGroup)
+	inner := g.AddGroup("inner", outer)
+	a := g.AddNode(testNode{X: 10, Y: 10, W: 5, H: 3})
+	b := g.AddNode(testNode{X: 20, Y: 10, W: 5, H: 3})
+	c := g.AddNode(testNode{X: 50, Y: 50, W: 5, H: 3}) // outside
+	g.SetParent(a, outer)
+	g.SetParent(b, inner)
+	g.Group(inner).Bounds = image.Rect(18, 8, 30, 15)
+
+	g.MoveGroup(outer, image.Pt(3, -2), setPos)
+
+	if d := g.Node(a).Data; d.X != 13 || d.Y != 8 {
+		t.Errorf("direct child: expected (13,8), got (%d,%d)", d.X, d.Y)
+	}
+	if d := g.Node(b).Data; d.X != 23 || d.Y != 8 {
+		t.Errorf("nested child: expected (23,8), got (%d,%d)", d.X, d.Y)
+	}
+	if d := g.Node(c).Data; d.X != 50 || d.Y != 50 {
+		t.Errorf("outside node moved to (%d,%d)", d.X, d.Y)
+	}
+	if got := g.Group(inner).Bounds; got != image.Rect(21, 6, 33, 13) {
+		t.Errorf("nested explicit bounds: expected (21,6)-(33,13), got %v", got)
+	}
+}
+
+// ── Collapsing ──
+
+func TestCollapsedHidesChildren(t *testing.T) {
+	g := New[testNode, string]()
+	outer := g.AddGroup("outer", NoGroup)
+	inner := g.AddGroup("inner", outer)
+	n := g.AddNode(testNode{X: 10, Y: 10, W: 5, H: 3})
+	g.SetParent(n, inner)
+
+	g.Group(outer).Collapsed = true
+	g.Group(inner).Collapsed = true
+	if !g.Hidden(n) {
+		t.Error("node in collapsed group should be hidden")
+	}
+	if g.CollapsedAncestor(n) != outer {
+		t.Errorf("CollapsedAncestor: expected outermost %d, got %d", outer, g.CollapsedAncestor(n))
+	}
+	if !g.GroupHidden(inner) || g.GroupHidden(outer) {
+		t.Error("inner group should be hidden, outer (the placeholder) should not")
+	}
+	if g.HitTest(image.Pt(12, 11)) != nil {
+		t.Error("HitTest should skip hidden nodes")
+	}
+}
+
+func TestCollapsedSize(t *testing.T) {
+	g := New[testNode, string]()
+	grp := g.AddGroup("g", NoGroup)
+	n := g.AddNode(testNode{X: 10, Y: 10, W: 20, H: 10})
+	g.SetParent(n, grp)
+	g.SetCollapsedSize(image.Pt(8, 3))
+	g.Group(grp).Collapsed = true
+
+	want := image.Rect(9, 9, 17, 12)
+	if got := g.GroupBounds(grp); got != want {
+		t.Errorf("collapsed bounds: expected %v, got %v", want, got)
+	}
+}
+
+// ── HitTestDeep ──
+
+func TestHitTestDeep(t *testing.T) {
+	g := New[testNode, string]()
+	outer := g.AddGroup("outer", NoGroup)
+	inner := g.AddGroup("inner", outer)
+	n := g.AddNode(testNode{X: 10, Y: 10, W: 5, H: 3})
+	g.SetParent(n, inner)
+	g.Group(outer).Bounds = image.Rect(0, 0, 40, 30)
+	// inner computed: (9,9)-(16,14)
+
+	tests := []struct {
+		name  string
+		pt    image.Point
+		kind  HitKind
+		group int
+	}{
+		{"node", image.Pt(12, 11), HitNode, inner},
+		{"inner frame", image.Pt(9, 11), HitGroupFrame, inner},
+		{"outer frame", image.Pt(0, 5), HitGroupFrame, outer},
+		{"outer body", image.Pt(30, 20), HitGroup, outer},
+		{"miss", image.Pt(50, 50), HitNone, NoGroup},
+	}
+	for _, tc := range tests {
+		hit := g.HitTestDeep(tc.pt)
+		if hit.Kind != tc.kind {
+			t.Errorf("%s: expected kind %d, got %d", tc.name, tc.kind, hit.Kind)
+			continue
+		}
+		gotGroup := NoGroup
+		if hit.Group != nil {
+			gotGroup = hit.Group.ID
+		}
+		if gotGroup != tc.group {
+			t.Errorf("%s: expected group %d, got %d", tc.name, tc.group, gotGroup)
+		}
+	}
+}
+
+func TestHitTestDeepCollapsed(t *testing.T) {
+	g := New[testNode, string]()
+	grp := g.AddGroup("g", NoGroup)
+	n := g.AddNode(testNode{X: 10, Y: 10, W: 5, H: 3})
+	g.SetParent(n, grp)
+	g.Group(grp).Collapsed = true
+
+	hit := g.HitTestDeep(image.Pt(12, 11))
+	if hit.Kind != HitGroupFrame || hit.Group == nil || hit.Group.ID != grp {
+		t.Errorf("collapsed placeholder: expected HitGroupFrame on %d, got %+v", grp, hit)
+	}
+}
@@ -0,0 +1,227 @@
+package graphmodel
+
+import (
+	"fmt"
+	"image"
+)
+
+// MergeOptions configures Merge3.
+type MergeOptions[N Spatial, E any] struct {
+	DiffOptions[N, E]
+	// SetPos has the same role as in MoveNode. Merge3 uses it to combine
+	// one side's position with the other side's data.
+	SetPos func(*N, image.Point)
+}
+
+// Conflict describes a change Merge3 could not apply cleanly. The merged
+// graph holds the resolution described by Reason.
+type Conflict struct {
+	ID     int
+	Edge   bool // ID is an edge ID rather than a node ID
+	Reason string
+}
+
+func (c Conflict) String() string {
+	kind := "node"
+	if c.Edge {
+		kind = "edge"
+	}
+	return fmt.Sprintf("%s %d: %s", kind, c.ID, c.Reason)
+}
+
+// Merge3 merges the changes made in ours and theirs since their common
+// ancestor base, matching nodes and edges by ID. Node positions, node
+// data, edge wiring and edge data merge independently, so one side may
+// move a node while the other edits it. The result never loses work:
+//
+//   - when both sides change the same field differently, ours wins;
+//   - a deletion on one side of an element modified on the other is
+//     undone;
+//   - elements added on both sides under the same ID but with different
+//     content are both kept, with theirs renumbered;
+//   - edges left without an endpoint are dropped.
+//
+// Each of these except renumbering is reported as a Conflict. Groups,
+// group membership and graph settings are taken from ours; nodes only in
+// theirs keep their group if ours still has it.
+func Merge3[N Spatial, E any](base, ours, theirs *Graph[N, E], opt MergeOptions[N, E]) (*Graph[N, E], []Conflict) {
+	m := New[N, E]()
+	m.multi = ours.multi
+	m.collapsedSize = ours.collapsedSize
+	for _, grp := range ours.Groups() {
+		m.InsertGroup(*grp)
+	}
+	m.nextID = max(base.nextID, ours.nextID, theirs.nextID)
+	m.nextEdgeID = max(base.nextEdgeID, ours.nextEdgeID, theirs.nextEdgeID)
+	m.nextGroupID = max(m.nextGroupID, ours.nextGroupID)
+
+	var conflicts []Conflict
+	conflict := func(id int, edge bool, format string, args ...any) {
+		conflicts = append(conflicts, Conflict{ID: id, Edge: edge, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	// ── Nodes ──
+
+	posEq := func(a, b image.Point) bool { return a == b }
+	nodeSame := func(a, b N) bool { return a.Pos() == b.Pos() && opt.NodeEqual(a, b) }
+	remap := make(map[int]int) // theirs node ID → merged ID, where they differ
+
+	add := func(id int, data N, from *Graph[N, E]) {
+		m.InsertNode(id, data)
+		m.SetParent(id, from.ParentOf(id)) // ignored if ours lacks the group
+	}
+
+	// Walk ours then theirs in z-order so the merged stacking follows ours.
+	var order []int
+	seen := make(map[int]bool)
+	for _, g := range []*Graph[N, E]{ours, theirs, base} {
+		for _, id := range g.orderIDs {
+			if !seen[id] {
+				seen[id] = true
+				order = append(order, id)
+			}
+		}
+	}
+
+	for _, id := range order {
+		b, o, t := base.nodes[id], ours.nodes[id], theirs.nodes[id]
+		switch {
+		case b != nil && o != nil && t != nil:
+			data, dataConflict := pick3(b.Data, o.Data, t.Data, opt.NodeEqual)
+			pos, posConflict := pick3(b.Data.Pos(), o.Data.Pos(), t.Data.Pos(), posEq)
+			opt.SetPos(&data, pos)
+			add(id, data, ours)
+			if dataConflict {
+				conflict(id, false, "both sides changed the node; kept ours")
+			}
+			if posConflict {
+				conflict(id, false, "both sides moved the node; kept our position")
+			}
+
+		case b != nil && o == nil && t != nil:
+			if !nodeSame(b.Data, t.Data) {
+				add(id, t.Data, theirs)
+				conflict(id, false, "deleted in ours but changed in theirs; kept theirs")
+			}
+
+		case b != nil && o != nil && t == nil:
+			if !nodeSame(b.Data, o.Data) {
+				add(id, o.Data, ours)
+				conflict(id, false, "deleted in theirs but changed in ours; kept ours")
+			}
+
+		case b == nil && o != nil && t != nil:
+			add(id, o.Data, ours)
+			if !nodeSame(o.Data, t.Data) {
+				newID := m.nextID
+				m.InsertNode(newID, t.Data)
+				m.SetParent(newID, theirs.ParentOf(id)) // ignored if ours lacks the group
+				remap[id] = newID
+			}
+
+		case b == nil && o != nil:
+			add(id, o.Data, ours)
+
+		case b == nil && t != nil:
+			add(id, t.Data, theirs)
+		}
+	}
+
+	// ── Edges ──
+
+	wiringEq := func(a, b Edge[E]) bool { return sameWiring(a, b) }
+	theirEdge := func(e Edge[E]) Edge[E] {
+		if id, ok := remap[e.FromID]; ok {
+			e.FromID = id
+		}
+		if id, ok := remap[e.ToID]; ok {
+			e.ToID = id
+		}
+		return e
+	}
+
+	eb, eo, et := edgeMap(base), edgeMap(ours), edgeMap(theirs)
+	var merged []Edge[E]
+	for _, id := range unionIDs(eb, eo, et) {
+		b, inB := eb[id]
+		o, inO := eo[id]
+		t, inT := et[id]
+		if inT {
+			t = theirEdge(t)
+		}
+		switch {
+		case inB && inO && inT:
+			e, wireConflict := pick3(b, o, t, wiringEq)
+			data, dataConflict := pick3(b.Data, o.Data, t.Data, opt.EdgeEqual)
+			e.Data = data
+			merged = append(merged, e)
+			if wireConflict {
+				conflict(id, true, "both sides rewired the edge; kept ours")
+			}
+			if dataConflict {
+				conflict(id, true, "both sides changed the edge; kept ours")
+			}
+
+		case inB && !inO && inT:
+			if !sameWiring(b, t) || !opt.EdgeEqual(b.Data, t.Data) {
+				merged = append(merged, t)
+				conflict(id, true, "deleted in ours but changed in theirs; kept theirs")
+			} else if implicitlyRemoved(ours, b) {
+				merged = append(merged, t)
+			}
+
+		case inB && inO && !inT:
+			if !sameWiring(b, o) || !opt.EdgeEqual(b.Data, o.Data) {
+				merged = append(merged, o)
+				conflict(id, true, "deleted in theirs but changed in ours; kept ours")
+			} else if implicitlyRemoved(theirs, b) {
+				merged = append(merged, o)
+			}
+
+		case !inB && inO && inT:
+			merged = append(merged, o)
+			if !sameWiring(o, t) || !opt.EdgeEqual(o.Data, t.Data) {
+				t.ID = m.nextEdgeID
+				m.nextEdgeID++
+				merged = append(merged, t)
+			}
+
+		case !inB && inO:
+			merged = append(merged, o)
+
+		case !inB && inT:
+			merged = append(merged, t)
+		}
+	}
+
+	for _, e := range merged {
+		if m.nodes[e.FromID] == nil || m.nodes[e.ToID] == nil {
+			if _, inBase := eb[e.ID]; !inBase || !sameWiring(eb[e.ID], e) || !opt.EdgeEqual(eb[e.ID].Data, e.Data) {
+				conflict(e.ID, true, "endpoint deleted; dropped the edge")
+			}
+			continue
+		}
+		m.InsertEdge(e)
+	}
+	return m, conflicts
+}
+
+// implicitlyRemoved reports whether e disappeared from g only because one
+// of its endpoints was deleted. Such an edge is kept if the merge restores
+// the endpoint; otherwise the final endpoint check drops it silently.
+func implicitlyRemoved[N Spatial, E any](g *Graph[N, E], e Edge[E]) bool {
+	return g.nodes[e.FromID] == nil || g.nodes[e.ToID] == nil
+}
+
+// pick3 merges one value three ways: the side that changed it relative
+// to base wins. If both changed it differently, ours is returned along
+// with true.
+func pick3[T any](base, ours, theirs T, eq func(a, b T) bool) (T, bool) {
+	switch {
+	case eq(ours, base):
+		return theirs, false
+	case eq(theirs, base), eq(ours, theirs):
+		return ours, false
+	}
+	return ours, true
+}
@@ -0,0 +1,151 @@
+package graphmodel
+
+import (
+	"image"
+	"testing"
+)
+
+var testMergeOpts = MergeOptions[testNode, string]{
+	DiffOptions: testDiffOpts,
+	SetPos:      setPos,
+}
+
+// mergeBase returns a two-node graph with one edge between them.
+func mergeBase() (g *Graph[testNode, string], n0, n1, e int) {
+	g = New[testNode, string]()
+	n0 = g.AddNode(testNode{X: 0, Y: 0, W: 5, H: 3})
+	n1 = g.AddNode(testNode{X: 10, Y: 0, W: 5, H: 3})
+	e = g.AddEdge(n0, n1, "")
+	return
+}
+
+func TestMerge3Independent(t *testing.T) {
+	base, n0, _, e := mergeBase()
+	ours, theirs := cloneGraph(base), cloneGraph(base)
+
+	ours.MoveNode(n0, image.Pt(2, 2), setPos)
+	theirs.Node(n0).Data.W = 9
+	theirs.Edge(e).Data = "Y"
+
+	m, conflicts := Merge3(base, ours, theirs, testMergeOpts)
+	if len(conflicts) != 0 {
+		t.Fatalf("expected no conflicts, got %v", conflicts)
+	}
+	if d := m.Node(n0).Data; d.X != 2 || d.Y != 2 || d.W != 9 {
+		t.Errorf("expected our move and their resize, got %+v", d)
+	}
+	if m.Edge(e).Data != "Y" {
+		t.Errorf("expected their label, got %q", m.Edge(e).Data)
+	}
+}
+
+func TestMerge3ConflictKeepsOurs(t *testing.T) {
+	base, n0, _, _ := mergeBase()
+	ours, theirs := cloneGraph(base), cloneGraph(base)
+
+	ours.Node(n0).Data.W = 7
+	theirs.Node(n0).Data.W = 9
+
+	m, conflicts := Merge3(base, ours, theirs, testMergeOpts)
+	if len(conflicts) != 1 || conflicts[0].ID != n0 || conflicts[0].Edge {
+		t.Fatalf("expected one node conflict on %d, got %v", n0, conflicts)
+	}
+	if m.Node(n0).Data.W != 7 {
+		t.Errorf("expected ours (W=7), got W=%d", m.Node(n0).Data.W)
+	}
+}
+
+func TestMerge3DeleteVsModify(t *testing.T) {
+	base, n0, n1, e := mergeBase()
+	ours, theirs := cloneGraph(base), cloneGraph(base)
+
+	ours.RemoveNode(n1)
+	theirs.MoveNode(n1, image.Pt(20, 5), setPos)
+
+	m, conflicts := Merge3(base, ours, theirs, testMergeOpts)
+	if len(conflicts) != 1 {
+		t.Fatalf("expected one conflict, got %v", conflicts)
+	}
+	if m.Node(n1) == nil || m.Node(n1).Data.X != 20 {
+		t.Error("modified node should survive the deletion")
+	}
+	if m.Edge(e) == nil || m.Edge(e).FromID != n0 {
+		t.Error("edge to the surviving node should be kept")
+	}
+}
+
+func TestMerge3CleanDelete(t *testing.T) {
+	base, _, n1, e := mergeBase()
+	ours, theirs := cloneGraph(base), cloneGraph(base)
+	ours.RemoveNode(n1)
+
+	m, conflicts := Merge3(base, ours, theirs, testMergeOpts)
+	if len(conflicts) != 0 {
+		t.Fatalf("expected no conflicts, got %v", conflicts)
+	}
+	if m.Node(n1) != nil {
+		t.Error("node deleted in ours should be gone")
+	}
+	if m.Edge(e) != nil {
+		t.Error("edge of the deleted node should be gone")
+	}
+}
+
+func TestMerge3DanglingEdge(t *testing.T) {
+	base, n0, n1, _ := mergeBase()
+	ours, theirs := cloneGraph(base), cloneGraph(base)
+	ours.RemoveNode(n1)
+	added := theirs.AddEdge(n1, n0, "back")
+
+	m, conflicts := Merge3(base, ours, theirs, testMergeOpts)
+	if m.Edge(added) != nil {
+		t.Error("edge to a deleted node should be dropped")
+	}
+	if len(conflicts) != 1 || !conflicts[0].Edge || conflicts[0].ID != added {
+		t.Errorf("expected one edge conflict on %d, got %v", added, conflicts)
+	}
+}
+
+func TestMerge3BothAdd(t *testing.T) {
+	base, n0, n1, _ := mergeBase()
+	ours, theirs := cloneGraph(base), cloneGraph(base)
+
+	oursNew := ours.AddNode(testNode{X: 0, Y: 20, W: 5, H: 3})
+	theirsNew := theirs.AddNode(testNode{X: 30, Y: 20, W: 5, H: 3})
+	theirs.AddEdge(n0, theirsNew, "")
+	if oursNew != theirsNew {
+		t.Fatal("test setup: both sides should allocate the same ID")
+	}
+
+	m, conflicts := Merge3(base, ours, theirs, testMergeOpts)
+	if len(conflicts) != 0 {
+		t.Fatalf("expected no conflicts, got %v", conflicts)
+	}
+	if len(m.Nodes()) != 4 {
+		t.Fatalf("expected 4 nodes, got %d", len(m.Nodes()))
+	}
+	if m.Node(oursNew).Data.X != 0 {
+		t.Error("our addition should keep its ID")
+	}
+	var target *Node[testNode]
+	for _, e := range m.OutEdges(n0) {
+		if e.ToID != n1 && e.ToID != oursNew {
+			target = m.Node(e.ToID)
+		}
+	}
+	if target == nil || target.Data.X != 30 {
+		t.Error("their edge should follow their renumbered node")
+	}
+}
+
+func TestMerge3KeepsOurGroups(t *testing.T) {
+	base, n0, _, _ := mergeBase()
+	ours, theirs := cloneGraph(base), cloneGraph(base)
+	grp := ours.AddGroup("g", NoGroup)
+	ours.SetParent(n0, grp)
+
+	m, _ := Merge3(base, ours, theirs, testMergeOpts)
+	if m.Group(grp) == nil || m.ParentOf(n0) != grp {
+		t.Error("groups and membership should come from ours")
+	}
+}
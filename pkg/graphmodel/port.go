@@ -0,0 +1,51 @@
+package graphmodel
+
+import "image"
+
+// Port is a named connection point on a node. Offset is relative to the
+// node's position (its top-left corner).
+type Port struct {
+	Name   string
+	Offset image.Point
+}
+
+// Ported is implemented by node data that exposes named ports. Nodes
+// without ports attach edges wherever the renderer decides.
+type Ported interface {
+	Ports() []Port
+}
+
+// PortsOf returns the ports of a Spatial element, or nil if it does not
+// implement Ported.
+func PortsOf(s Spatial) []Port {
+	if p, ok := s.(Ported); ok {
+		return p.Ports()
+	}
+	return nil
+}
+
+// PortPos returns the absolute position of the named port, and false if
+// the element has no such port.
+func PortPos(s Spatial, name string) (image.Point, bool) {
+	for _, p := range PortsOf(s) {
+		if p.Name == name {
+			return s.Pos().Add(p.Offset), true
+		}
+	}
+	return image.Point{}, false
+}
+
+// NearestPort returns the port whose absolute position is closest to pt,
+// and false if the element has no ports. Ties go to the earlier port.
+func NearestPort(s Spatial, pt image.Point) (Port, bool) {
+	var best Port
+	bestDist := -1
+	for _, p := range PortsOf(s) {
+		d := s.Pos().Add(p.Offset).Sub(pt)
+		dist := d.X*d.X + d.Y*d.Y
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = p, dist
+		}
+	}
+	return best, bestDist >= 0
+}
@@ -0,0 +1,73 @@
+package graphmodel
+
+import (
+	"image"
+	"testing"
+)
+
+// portedNode is a testNode with top/bottom/right ports.
+type portedNode struct {
+	testNode
+}
+
+func (n portedNode) Ports() []Port {
+	return []Port{
+		{Name: "in", Offset: image.Pt(n.W/2, 0)},
+		{Name: "out", Offset: image.Pt(n.W/2, n.H-1)},
+		{Name: "side", Offset: image.Pt(n.W-1, n.H/2)},
+	}
+}
+
+func TestPortsOfNonPorted(t *testing.T) {
+	if PortsOf(testNode{W: 5, H: 3}) != nil {
+		t.Error("testNode has no ports, expected nil")
+	}
+	if _, ok := NearestPort(testNode{W: 5, H: 3}, image.Pt(0, 0)); ok {
+		t.Error("NearestPort on a node without ports should fail")
+	}
+}
+
+func TestPortPos(t *testing.T) {
+	n := portedNode{testNode{X: 10, Y: 20, W: 8, H: 3}}
+	p, ok := PortPos(n, "out")
+	if !ok || p != image.Pt(14, 22) {
+		t.Errorf("PortPos(out): expected (14,22), got %v ok=%v", p, ok)
+	}
+	if _, ok := PortPos(n, "missing"); ok {
+		t.Error("PortPos of unknown port should fail")
+	}
+}
+
+func TestNearestPort(t *testing.T) {
+	n := portedNode{testNode{X: 10, Y: 20, W: 8, H: 3}}
+	tests := []struct {
+		pt   image.Point
+		want string
+	}{
+		{image.Pt(14, 15), "in"},
+		{image.Pt(13, 30), "out"},
+		{image.Pt(30, 21), "side"},
+	}
+	for _, tc := range tests {
+		p, ok := NearestPort(n, tc.pt)
+		if !ok || p.Name != tc.want {
+			t.Errorf("NearestPort(%v): expected %q, got %q", tc.pt, tc.want, p.Name)
+		}
+	}
+}
+
+func TestAddPortEdge(t *testing.T) {
+	g := New[portedNode, string]()
+	a := g.AddNode(portedNode{testNode{X: 0, Y: 0, W: 8, H: 3}})
+	b := g.AddNode(portedNode{testNode{X: 0, Y: 10, W: 8, H: 3}})
+	id := g.AddPortEdge(a, "out", b, "in", "")
+	e := g.Edge(id)
+	if e == nil || e.FromPort != "out" || e.ToPort != "in" {
+		t.Errorf("AddPortEdge: unexpected edge %+v", e)
+	}
+	// Plain AddEdge leaves ports empty
+	e = g.Edge(g.AddEdge(b, a, ""))
+	if e.FromPort != "" || e.ToPort != "" {
+		t.Errorf("AddEdge should not set ports, got %q/%q", e.FromPort, e.ToPort)
+	}
+}
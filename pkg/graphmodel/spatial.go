@@ -1,5 +1,5 @@
 // Package graphmodel provides a generic spatial graph with positioned nodes,
-// labeled edges, stable iteration order, and hit testing.
+// labeled edges, nested groups, stable iteration order, and hit testing.
 package graphmodel
 
 import "image"